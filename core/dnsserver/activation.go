@@ -0,0 +1,135 @@
+package dnsserver
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// Socket activation: a parent process, like systemd, opens the sockets and passes them to us as file
+// descriptors starting at 3. LISTEN_FDS holds the number of descriptors and LISTEN_PID our process id.
+// See sd_listen_fds(3). This allows CoreDNS to run unprivileged and still serve on port 53.
+
+// listenFdsStart is the first file descriptor passed by socket activation.
+const listenFdsStart = 3
+
+// activation holds the sockets passed by socket activation that haven't been picked up yet.
+var activation struct {
+	sync.Once
+	sync.Mutex
+	sockets []*activated
+}
+
+// activated is a socket passed by socket activation, either ln or pc is set.
+type activated struct {
+	ln   net.Listener
+	pc   net.PacketConn
+	ip   net.IP // address the socket is bound to
+	port int
+}
+
+// activationFiles returns the files passed by socket activation, if any, and clears the environment
+// so child processes don't pick them up.
+func activationFiles() []*os.File {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	files := make([]*os.File, n)
+	for i := range files {
+		fd := listenFdsStart + i
+		files[i] = os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+	}
+	return files
+}
+
+// inherit turns files into listeners and packet conns. Files that aren't TCP or UDP sockets are
+// skipped. The files themselves are closed.
+func inherit(files []*os.File) []*activated {
+	var sockets []*activated
+	for _, f := range files {
+		if ln, err := net.FileListener(f); err == nil {
+			if a, ok := ln.Addr().(*net.TCPAddr); ok {
+				sockets = append(sockets, &activated{ln: ln, ip: a.IP, port: a.Port})
+			} else {
+				ln.Close()
+				log.Warningf("Ignoring socket activated listener on %s: not TCP", ln.Addr())
+			}
+		} else if pc, err := net.FilePacketConn(f); err == nil {
+			if a, ok := pc.LocalAddr().(*net.UDPAddr); ok {
+				sockets = append(sockets, &activated{pc: pc, ip: a.IP, port: a.Port})
+			} else {
+				pc.Close()
+				log.Warningf("Ignoring socket activated packet conn on %s: not UDP", pc.LocalAddr())
+			}
+		} else {
+			log.Warningf("Ignoring socket activated file %s: %s", f.Name(), err)
+		}
+		f.Close()
+	}
+	return sockets
+}
+
+func loadActivation() {
+	activation.Do(func() {
+		activation.sockets = inherit(activationFiles())
+	})
+}
+
+// activatedListener returns the TCP listener for addr passed by socket activation, or nil if there isn't
+// one. Each listener is only handed out once.
+func activatedListener(addr string) net.Listener {
+	loadActivation()
+	activation.Lock()
+	defer activation.Unlock()
+	for i, s := range activation.sockets {
+		if s.ln != nil && matchAddr(addr, s.ip, s.port) {
+			activation.sockets = append(activation.sockets[:i], activation.sockets[i+1:]...)
+			return s.ln
+		}
+	}
+	return nil
+}
+
+// activatedPacketConn returns the UDP packet conn for addr passed by socket activation, or nil if there
+// isn't one. Each packet conn is only handed out once.
+func activatedPacketConn(addr string) net.PacketConn {
+	loadActivation()
+	activation.Lock()
+	defer activation.Unlock()
+	for i, s := range activation.sockets {
+		if s.pc != nil && matchAddr(addr, s.ip, s.port) {
+			activation.sockets = append(activation.sockets[:i], activation.sockets[i+1:]...)
+			return s.pc
+		}
+	}
+	return nil
+}
+
+// matchAddr reports if a socket bound to ip and port serves addr. The ports must be equal; an addr
+// without an address, or with the unspecified address, matches any ip.
+func matchAddr(addr string, ip net.IP, port int) bool {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil || p != strconv.Itoa(port) {
+		return false
+	}
+	if host == "" {
+		return true
+	}
+	want := net.ParseIP(host)
+	if want == nil {
+		return false
+	}
+	return want.IsUnspecified() || want.Equal(ip)
+}
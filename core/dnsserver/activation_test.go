@@ -0,0 +1,86 @@
+package dnsserver
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestMatchAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		ip       string
+		port     int
+		expected bool
+	}{
+		{":53", "0.0.0.0", 53, true},
+		{":53", "192.0.2.1", 53, true},
+		{":53", "::", 53, true},
+		{":53", "0.0.0.0", 1053, false},
+		{"0.0.0.0:53", "192.0.2.1", 53, true},
+		{"[::]:53", "192.0.2.1", 53, true},
+		{"192.0.2.1:53", "192.0.2.1", 53, true},
+		{"192.0.2.1:53", "192.0.2.2", 53, false},
+		{"192.0.2.1:53", "0.0.0.0", 53, false},
+		{"[2001:db8::1]:53", "2001:db8::1", 53, true},
+		{"localhost:53", "127.0.0.1", 53, false},
+		{"53", "0.0.0.0", 53, false},
+	}
+	for i, tc := range tests {
+		if x := matchAddr(tc.addr, net.ParseIP(tc.ip), tc.port); x != tc.expected {
+			t.Errorf("Test %d: expected %t for %s and %s:%d, got %t", i, tc.expected, tc.addr, tc.ip, tc.port, x)
+		}
+	}
+}
+
+func TestActivation(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+	p, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer p.Close()
+
+	lf, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get file: %s", err)
+	}
+	pf, err := p.(*net.UDPConn).File()
+	if err != nil {
+		t.Fatalf("Failed to get file: %s", err)
+	}
+
+	loadActivation()
+	activation.sockets = inherit([]*os.File{lf, pf})
+	defer func() { activation.sockets = nil }()
+	if len(activation.sockets) != 2 {
+		t.Fatalf("Expected 2 sockets, got %d", len(activation.sockets))
+	}
+
+	laddr, paddr := l.Addr().String(), p.LocalAddr().String()
+	if ln := activatedListener("127.0.0.2" + laddr[len("127.0.0.1"):]); ln != nil {
+		t.Errorf("Expected no listener for another address, got %s", ln.Addr())
+	}
+
+	ln := activatedListener(laddr)
+	if ln == nil {
+		t.Fatalf("Expected listener for %s", laddr)
+	}
+	defer ln.Close()
+	if activatedListener(laddr) != nil {
+		t.Errorf("Expected listener to be handed out once")
+	}
+
+	pc := activatedPacketConn(paddr)
+	if pc == nil {
+		t.Fatalf("Expected packet conn for %s", paddr)
+	}
+	defer pc.Close()
+	if _, ok := pc.(*net.UDPConn); !ok {
+		t.Errorf("Expected a *net.UDPConn, got %T", pc)
+	}
+}
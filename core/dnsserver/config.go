@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 
 	"github.com/mholt/caddy"
 )
@@ -40,6 +41,25 @@ type Config struct {
 	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
 	TLSConfig *tls.Config
 
+	// ProxyProtocol, when not nil, makes the listeners accept a PROXY protocol header from the
+	// trusted sources in it.
+	ProxyProtocol *proxyproto.Config
+
+	// TCP, when not nil, holds the limits and timeouts for TCP and DNS-over-TLS connections.
+	TCP *TCPConfig
+
+	// NoCookies disables server cookies (RFC 7873): the COOKIE option of queries is ignored.
+	NoCookies bool
+
+	// ReusePort is the number of UDP sockets opened with SO_REUSEPORT on the server's address, each
+	// with its own read loop. 0 and 1 mean a single socket.
+	ReusePort int
+
+	// MaxConcurrent is the maximum number of queries the server handles concurrently, 0 is unlimited.
+	// Queries over the limit are answered with ConcurrencyRcode.
+	MaxConcurrent    int
+	ConcurrencyRcode int
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
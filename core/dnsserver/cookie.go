@@ -0,0 +1,187 @@
+package dnsserver
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// cookieSecret holds the secrets used to create and validate server cookies (RFC 7873). The secret is
+// rotated every cookieRotate; cookies created with the previous secret are still accepted. It lives
+// outside of the Server so cookies handed out stay valid across reloads.
+type cookieSecret struct {
+	sync.RWMutex
+	current  []byte
+	previous []byte
+	rotated  time.Time
+}
+
+var cookies = newCookieSecret()
+
+func newCookieSecret() *cookieSecret {
+	c := &cookieSecret{}
+	c.rotate(time.Now())
+	return c
+}
+
+func (c *cookieSecret) rotate(now time.Time) {
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	c.previous, c.current = c.current, secret
+	c.rotated = now
+}
+
+// secrets returns the current and the previous secret, rotating them first when needed.
+func (c *cookieSecret) secrets(now time.Time) (current, previous []byte) {
+	c.RLock()
+	if now.Sub(c.rotated) < cookieRotate {
+		current, previous = c.current, c.previous
+		c.RUnlock()
+		return current, previous
+	}
+	c.RUnlock()
+
+	c.Lock()
+	if now.Sub(c.rotated) >= cookieRotate {
+		c.rotate(now)
+	}
+	current, previous = c.current, c.previous
+	c.Unlock()
+	return current, previous
+}
+
+// serverCookie returns the server cookie for the client cookie cc sent from ip. It uses the layout
+// from RFC 9018: version, 3 reserved bytes, a timestamp and a hash, for which we use a truncated
+// HMAC-SHA256.
+func serverCookie(secret, cc []byte, ts uint32, ip net.IP) []byte {
+	b := make([]byte, 8, 8+sha256.Size)
+	b[0] = 1
+	binary.BigEndian.PutUint32(b[4:], ts)
+
+	h := hmac.New(sha256.New, secret)
+	h.Write(cc)
+	h.Write(b)
+	h.Write(ip.To16())
+	return h.Sum(b)[:16]
+}
+
+// validCookie returns true if cookie holds a server cookie we created for ip with one of the secrets
+// and that hasn't expired.
+func validCookie(cookie []byte, ip net.IP, now time.Time, secrets ...[]byte) bool {
+	if len(cookie) != 24 || cookie[8] != 1 {
+		return false
+	}
+	ts := binary.BigEndian.Uint32(cookie[12:16])
+	t := time.Unix(int64(ts), 0)
+	if t.Before(now.Add(-cookieLifetime)) || t.After(now.Add(cookieSkew)) {
+		return false
+	}
+	for _, s := range secrets {
+		if s == nil {
+			continue
+		}
+		if hmac.Equal(serverCookie(s, cookie[:8], ts, ip), cookie[8:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// cookie inspects the COOKIE option in r. It returns the response writer to use, which puts a fresh
+// server cookie in the reply, and the rcode r should be answered with right away. For requests that
+// can be handled normally dns.RcodeSuccess is returned.
+func (s *Server) cookie(w dns.ResponseWriter, r *dns.Msg) (dns.ResponseWriter, int) {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return w, dns.RcodeSuccess
+	}
+	var c *dns.EDNS0_COOKIE
+	for _, o := range opt.Option {
+		if e, ok := o.(*dns.EDNS0_COOKIE); ok {
+			c = e
+			break
+		}
+	}
+	if c == nil {
+		return w, dns.RcodeSuccess
+	}
+
+	cookie, err := hex.DecodeString(c.Cookie)
+	if err != nil || (len(cookie) != 8 && (len(cookie) < 16 || len(cookie) > 40)) {
+		vars.RequestCookie.WithLabelValues(s.Addr, "malformed").Inc()
+		return &cookieWriter{ResponseWriter: w}, dns.RcodeFormatError
+	}
+
+	state := request.Request{W: w, Req: r}
+	ip := net.ParseIP(state.IP())
+	now := time.Now()
+	current, previous := cookies.secrets(now)
+
+	sc := serverCookie(current, cookie[:8], uint32(now.Unix()), ip)
+	cw := &cookieWriter{ResponseWriter: w, cookie: hex.EncodeToString(cookie[:8]) + hex.EncodeToString(sc)}
+
+	switch {
+	case len(cookie) == 8:
+		vars.RequestCookie.WithLabelValues(s.Addr, "new").Inc()
+	case validCookie(cookie, ip, now, current, previous):
+		vars.RequestCookie.WithLabelValues(s.Addr, "valid").Inc()
+	default:
+		vars.RequestCookie.WithLabelValues(s.Addr, "invalid").Inc()
+		// Over TCP the client can't be spoofed, just hand out a new cookie.
+		if state.Proto() == "udp" {
+			return cw, dns.RcodeBadCookie
+		}
+	}
+	return cw, dns.RcodeSuccess
+}
+
+// cookieWriter sets the COOKIE option in the reply to cookie. If cookie is empty the option is removed.
+type cookieWriter struct {
+	dns.ResponseWriter
+	cookie string
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (c *cookieWriter) WriteMsg(m *dns.Msg) error {
+	// Don't change the OPT record in place, it may be the one from the request.
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	i := len(m.Extra)
+	for j, rr := range m.Extra {
+		if o, ok := rr.(*dns.OPT); ok {
+			opt.Hdr = o.Hdr
+			for _, e := range o.Option {
+				if e.Option() != dns.EDNS0COOKIE {
+					opt.Option = append(opt.Option, e)
+				}
+			}
+			i = j
+			break
+		}
+	}
+	if c.cookie != "" {
+		opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: c.cookie})
+	}
+	if i == len(m.Extra) {
+		m.Extra = append(m.Extra, opt)
+	} else {
+		m.Extra[i] = opt
+	}
+
+	return c.ResponseWriter.WriteMsg(m)
+}
+
+const (
+	cookieRotate   = 1 * time.Hour
+	cookieLifetime = 1 * time.Hour
+	cookieSkew     = 5 * time.Minute
+)
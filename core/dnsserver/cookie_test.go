@@ -0,0 +1,159 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func answerPlugin() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		state := request.Request{W: w, Req: r}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		state.SizeAndDo(m)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func cookieMsg(cookie string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	if cookie != "" {
+		o := m.IsEdns0()
+		o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+	}
+	return m
+}
+
+func replyCookie(m *dns.Msg) string {
+	o := m.IsEdns0()
+	if o == nil {
+		return ""
+	}
+	for _, e := range o.Option {
+		if c, ok := e.(*dns.EDNS0_COOKIE); ok {
+			return c.Cookie
+		}
+	}
+	return ""
+}
+
+func TestCookie(t *testing.T) {
+	s, err := NewServer("127.0.0.1:53", []*Config{testConfig("dns", answerPlugin())})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	ctx := context.TODO()
+	const client = "0102030405060708"
+
+	serve := func(m *dns.Msg, tcp bool) *dns.Msg {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tcp})
+		s.ServeDNS(ctx, rec, m)
+		if rec.Msg == nil {
+			t.Fatal("Expected a reply, got none")
+		}
+		return rec.Msg
+	}
+
+	// No cookie, no cookie in the reply.
+	if c := replyCookie(serve(cookieMsg(""), false)); c != "" {
+		t.Errorf("Expected no cookie, got %s", c)
+	}
+
+	// Client cookie only, we hand out a server cookie.
+	reply := serve(cookieMsg(client), false)
+	if reply.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[reply.Rcode])
+	}
+	cookie := replyCookie(reply)
+	if len(cookie) != 48 || cookie[:16] != client {
+		t.Fatalf("Expected client and server cookie, got %s", cookie)
+	}
+
+	// Valid server cookie.
+	reply = serve(cookieMsg(cookie), false)
+	if reply.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[reply.Rcode])
+	}
+	if c := replyCookie(reply); c[:16] != client {
+		t.Errorf("Expected client cookie %s to be echoed, got %s", client, c)
+	}
+
+	// Invalid server cookie, BADCOOKIE over UDP with a new server cookie, answered over TCP.
+	bad := client + "01000000" + cookie[24:32] + "0000000000000000"
+	reply = serve(cookieMsg(bad), false)
+	if reply.Rcode != dns.RcodeBadCookie {
+		t.Errorf("Expected BADCOOKIE, got %s", dns.RcodeToString[reply.Rcode])
+	}
+	if c := replyCookie(reply); len(c) != 48 || c == bad {
+		t.Errorf("Expected new server cookie, got %s", c)
+	}
+	if reply = serve(cookieMsg(bad), true); reply.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR over TCP, got %s", dns.RcodeToString[reply.Rcode])
+	}
+
+	// Malformed cookie.
+	reply = serve(cookieMsg("0102"), false)
+	if reply.Rcode != dns.RcodeFormatError {
+		t.Errorf("Expected FORMERR, got %s", dns.RcodeToString[reply.Rcode])
+	}
+	if c := replyCookie(reply); c != "" {
+		t.Errorf("Expected no cookie, got %s", c)
+	}
+}
+
+func TestValidCookie(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	cc := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	ip := net.ParseIP("10.240.0.1")
+	now := time.Now()
+
+	cookie := append(cc, serverCookie(secret, cc, uint32(now.Unix()), ip)...)
+	if !validCookie(cookie, ip, now, nil, secret) {
+		t.Errorf("Expected cookie %s to be valid", hex.EncodeToString(cookie))
+	}
+	if validCookie(cookie, net.ParseIP("10.240.0.2"), now, secret) {
+		t.Error("Expected cookie for other client to be invalid")
+	}
+	if validCookie(cookie, ip, now, []byte("other secret")) {
+		t.Error("Expected cookie with other secret to be invalid")
+	}
+	if validCookie(cookie, ip, now.Add(2*cookieLifetime), secret) {
+		t.Error("Expected expired cookie to be invalid")
+	}
+}
+
+func TestNoCookies(t *testing.T) {
+	cfg := testConfig("dns", answerPlugin())
+	cfg.NoCookies = true
+	s, err := NewServer("127.0.0.1:53", []*Config{cfg})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	// An invalid server cookie is not answered with BADCOOKIE and no new cookie is handed out.
+	bad := "0102030405060708" + "0100000000000000" + "0000000000000000"
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, cookieMsg(bad))
+	if rec.Msg == nil {
+		t.Fatal("Expected a reply, got none")
+	}
+	if rec.Msg.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if c := replyCookie(rec.Msg); c != "" && c != bad {
+		t.Errorf("Expected no new server cookie, got %s", c)
+	}
+}
@@ -0,0 +1,99 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/miekg/dns"
+)
+
+// serveProxyPacket serves the packets read from p. Packets from trusted sources must start with a
+// PROXY protocol header, the client address in it is used as the remote address of the request.
+// The dns package only serves a *net.UDPConn as is, so we read the packets ourselves. The caller must
+// add p to s.packets, so it is closed on Stop.
+func (s *Server) serveProxyPacket(p net.PacketConn) error {
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, addr, err := p.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return err
+		}
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		go s.serveProxyPacketMsg(p, b, addr)
+	}
+}
+
+func (s *Server) serveProxyPacketMsg(p net.PacketConn, b []byte, addr net.Addr) {
+	w := &packetWriter{PacketConn: p, addr: addr, remote: addr}
+	if s.proxyProtocol.Trusted(addr) {
+		src, _, n, err := proxyproto.Parse(b)
+		if err != nil {
+			log.Debugf("Dropping packet from %s: %s", addr, err)
+			return
+		}
+		b = b[n:]
+		if src != nil {
+			w.remote = udpAddr(src)
+		}
+	}
+
+	m := new(dns.Msg)
+	if err := m.Unpack(b); err != nil {
+		// Like the dns package, let the client hang; any reply can be used to amplify.
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), Key{}, s)
+	s.ServeDNS(ctx, w, m)
+}
+
+// udpAddr returns addr as a *net.UDPAddr, so the request is seen as one that came in over UDP.
+func udpAddr(addr net.Addr) net.Addr {
+	if a, ok := addr.(*net.TCPAddr); ok {
+		return &net.UDPAddr{IP: a.IP, Port: a.Port, Zone: a.Zone}
+	}
+	return addr
+}
+
+// packetWriter is the dns.ResponseWriter for packets served by serveProxyPacket. Replies are sent to
+// addr, which is the load balancer for proxied packets, while RemoteAddr returns the client's address.
+type packetWriter struct {
+	net.PacketConn
+	addr   net.Addr
+	remote net.Addr
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *packetWriter) WriteMsg(m *dns.Msg) error {
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *packetWriter) Write(buf []byte) (int, error) { return w.WriteTo(buf, w.addr) }
+
+// RemoteAddr implements the dns.ResponseWriter interface.
+func (w *packetWriter) RemoteAddr() net.Addr { return w.remote }
+
+// Close implements the dns.ResponseWriter interface. The packet connection is shared, so this is a noop.
+func (w *packetWriter) Close() error { return nil }
+
+// TsigStatus implements the dns.ResponseWriter interface.
+func (w *packetWriter) TsigStatus() error { return nil }
+
+// TsigTimersOnly implements the dns.ResponseWriter interface.
+func (w *packetWriter) TsigTimersOnly(bool) {}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *packetWriter) Hijack() {}
@@ -0,0 +1,39 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+)
+
+// reusePort opens s.reuseport-1 more UDP sockets on the address of p and serves them, so the reading
+// of packets is spread over multiple sockets and goroutines. The kernel balances the packets over the
+// sockets, which needs SO_REUSEPORT to be set on all of them. If a socket can't be opened the server
+// continues with the sockets it has.
+func (s *Server) reusePort(p net.PacketConn) {
+	for i := 1; i < s.reuseport; i++ {
+		pc, err := listenPacket("udp", p.LocalAddr().String())
+		if err != nil {
+			log.Warningf("Failed to open UDP socket %d of %d on %s: %s", i+1, s.reuseport, p.LocalAddr(), err)
+			return
+		}
+
+		s.m.Lock()
+		s.packets = append(s.packets, pc)
+		s.m.Unlock()
+
+		if s.proxyProtocol != nil {
+			go s.serveProxyPacket(pc)
+			continue
+		}
+		srv := &dns.Server{PacketConn: pc, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			ctx := context.WithValue(context.Background(), Key{}, s)
+			s.ServeDNS(ctx, w, r)
+		})}
+		// Closing the socket in Stop ends this server.
+		go srv.ActivateAndServe()
+	}
+}
@@ -0,0 +1,51 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReusePort(t *testing.T) {
+	c := testConfig("dns", testPlugin{})
+	c.ReusePort = 3
+	s, err := NewServer("dns://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	s.graceTimeout = 0
+
+	p, err := listenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	done := make(chan error)
+	go func() { done <- s.ServePacket(p) }()
+
+	// ServePacket opens the other sockets before it starts serving.
+	for i := 0; i < 100; i++ {
+		s.m.Lock()
+		n := len(s.packets)
+		s.m.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.m.Lock()
+	for _, pc := range s.packets {
+		if pc.LocalAddr().String() != p.LocalAddr().String() {
+			t.Errorf("Expected socket on %s, got %s", p.LocalAddr(), pc.LocalAddr())
+		}
+	}
+	if len(s.packets) != 2 {
+		t.Errorf("Expected 2 more sockets, got %d", len(s.packets))
+	}
+	s.m.Unlock()
+
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected server to stop")
+	}
+}
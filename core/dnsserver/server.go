@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/plugin/pkg/trace"
 	"github.com/coredns/coredns/plugin/pkg/transport"
@@ -20,6 +23,7 @@ import (
 
 	"github.com/miekg/dns"
 	ot "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Server represents an instance of a server, which serves
@@ -28,6 +32,8 @@ import (
 // the same address and the listener may be stopped for
 // graceful termination (POSIX only).
 type Server struct {
+	inflight int64 // queries in flight, accessed atomically; first in the struct for 64 bit alignment
+
 	Addr string // Address we listen on
 
 	server [2]*dns.Server // 0 is a net.Listener, 1 is a net.PacketConn (a *UDPConn) in our case.
@@ -39,6 +45,17 @@ type Server struct {
 	trace        trace.Trace        // the trace plugin for the server
 	debug        bool               // disable recover()
 	classChaos   bool               // allow non-INET class queries
+
+	proxyProtocol *proxyproto.Config // accept PROXY protocol headers from trusted sources
+	packets       []net.PacketConn   // served without the dns.Server in server, closed on Stop
+	tcp           *TCPConfig         // limits and timeouts for TCP connections
+	padding       bool               // pad replies, set for encrypted transports
+	noCookies     bool               // don't validate or hand out server cookies
+	reuseport     int                // number of UDP sockets to serve
+
+	maxConcurrent    int64            // maximum number of queries in flight, 0 is unlimited
+	concurrencyRcode int              // rcode for queries over the maxConcurrent limit
+	inflightGauge    prometheus.Gauge // reports inflight
 }
 
 // NewServer returns a new CoreDNS server and compiles all plugins in to it. By default CH class
@@ -49,6 +66,8 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		Addr:         addr,
 		zones:        make(map[string]*Config),
 		graceTimeout: 5 * time.Second,
+
+		inflightGauge: vars.RequestsInFlight.WithLabelValues(addr),
 	}
 
 	// We have to bound our wg with one increment
@@ -66,6 +85,22 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		}
 		// set the config per zone
 		s.zones[site.Zone] = site
+		if site.ProxyProtocol != nil {
+			s.proxyProtocol = site.ProxyProtocol
+		}
+		if site.TCP != nil {
+			s.tcp = site.TCP
+		}
+		if site.NoCookies {
+			s.noCookies = true
+		}
+		if site.ReusePort > 0 {
+			s.reuseport = site.ReusePort
+		}
+		if site.MaxConcurrent > 0 {
+			s.maxConcurrent = int64(site.MaxConcurrent)
+			s.concurrencyRcode = site.ConcurrencyRcode
+		}
 
 		// compile custom plugin for everything
 		var stack plugin.Handler
@@ -101,6 +136,7 @@ func (s *Server) Serve(l net.Listener) error {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		s.ServeDNS(ctx, w, r)
 	})}
+	s.setTCPOptions(s.server[tcp])
 	s.m.Unlock()
 
 	return s.server[tcp].ActivateAndServe()
@@ -109,6 +145,15 @@ func (s *Server) Serve(l net.Listener) error {
 // ServePacket starts the server with an existing packetconn. It blocks until the server stops.
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
+	s.reusePort(p)
+
+	if s.proxyProtocol != nil {
+		s.m.Lock()
+		s.packets = append(s.packets, p)
+		s.m.Unlock()
+		return s.serveProxyPacket(p)
+	}
+
 	s.m.Lock()
 	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
@@ -121,21 +166,40 @@ func (s *Server) ServePacket(p net.PacketConn) error {
 
 // Listen implements caddy.TCPServer interface.
 func (s *Server) Listen() (net.Listener, error) {
-	l, err := listen("tcp", s.Addr[len(transport.DNS+"://"):])
+	addr := s.Addr[len(transport.DNS+"://"):]
+	if l := activatedListener(addr); l != nil {
+		return s.WrapListener(l), nil
+	}
+	l, err := listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
-	return l, nil
+	return s.WrapListener(l), nil
 }
 
-// WrapListener Listen implements caddy.GracefulServer interface.
+// WrapListener Listen implements caddy.GracefulServer interface. It applies the TCP connection limits
+// of plain DNS and DNS-over-TLS servers and, when the PROXY protocol is enabled, connections from
+// trusted sources must start with a PROXY protocol header.
 func (s *Server) WrapListener(ln net.Listener) net.Listener {
+	if ln == nil {
+		return ln
+	}
+	if s.tcp != nil && (strings.HasPrefix(s.Addr, transport.DNS+"://") || strings.HasPrefix(s.Addr, transport.TLS+"://")) {
+		ln = newLimitListener(ln, s.Addr, s.tcp)
+	}
+	if s.proxyProtocol != nil {
+		ln = proxyproto.NewListener(ln, s.proxyProtocol)
+	}
 	return ln
 }
 
 // ListenPacket implements caddy.UDPServer interface.
 func (s *Server) ListenPacket() (net.PacketConn, error) {
-	p, err := listenPacket("udp", s.Addr[len(transport.DNS+"://"):])
+	addr := s.Addr[len(transport.DNS+"://"):]
+	if p := activatedPacketConn(addr); p != nil {
+		return p, nil
+	}
+	p, err := listenPacket("udp", addr)
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +240,9 @@ func (s *Server) Stop() (err error) {
 			err = s1.Shutdown()
 		}
 	}
+	for _, p := range s.packets {
+		err = p.Close()
+	}
 	s.m.Unlock()
 	return
 }
@@ -216,6 +283,20 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		return
 	}
 
+	// Lookups we do on behalf of a query (see plugin/pkg/upstream) are part of that query, they don't
+	// take another slot.
+	if _, internal := ctx.Value(InternalKey{}).(bool); !internal {
+		n := atomic.AddInt64(&s.inflight, 1)
+		defer atomic.AddInt64(&s.inflight, -1)
+		s.inflightGauge.Inc()
+		defer s.inflightGauge.Dec()
+		if s.maxConcurrent > 0 && n > s.maxConcurrent {
+			vars.RequestOverload.WithLabelValues(s.Addr).Inc()
+			errorAndMetricsFunc(s.Addr, w, r, s.concurrencyRcode)
+			return
+		}
+	}
+
 	q := r.Question[0].Name
 	b := make([]byte, len(q))
 	var off int
@@ -224,7 +305,20 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	var dshandler *Config
 
 	// Wrap the response writer in a ScrubWriter so we automatically make the reply fit in the client's buffer.
-	w = request.NewScrubWriter(r, w)
+	if s.padding {
+		w = request.NewPaddingScrubWriter(r, w)
+	} else {
+		w = request.NewScrubWriter(r, w)
+	}
+
+	// Validate the DNS cookie, if any, and make the reply carry a fresh server cookie.
+	if !s.noCookies {
+		var rc int
+		if w, rc = s.cookie(w, r); rc != dns.RcodeSuccess {
+			errorAndMetricsFunc(s.Addr, w, r, rc)
+			return
+		}
+	}
 
 	for {
 		l := len(q[off:])
@@ -239,18 +333,18 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		if h, ok := s.zones[string(b[:l])]; ok {
 			if r.Question[0].Qtype != dns.TypeDS {
 				if h.FilterFunc == nil {
-					rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+					rcode, err := h.pluginChain.ServeDNS(ctx, w, r)
 					if !plugin.ClientWrite(rcode) {
-						errorFunc(s.Addr, w, r, rcode)
+						errorFunc(s.Addr, w, r, rcode, err)
 					}
 					return
 				}
 				// FilterFunc is set, call it to see if we should use this handler.
 				// This is given to full query name.
 				if h.FilterFunc(q) {
-					rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+					rcode, err := h.pluginChain.ServeDNS(ctx, w, r)
 					if !plugin.ClientWrite(rcode) {
-						errorFunc(s.Addr, w, r, rcode)
+						errorFunc(s.Addr, w, r, rcode, err)
 					}
 					return
 				}
@@ -270,18 +364,18 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	if r.Question[0].Qtype == dns.TypeDS && dshandler != nil && dshandler.pluginChain != nil {
 		// DS request, and we found a zone, use the handler for the query.
-		rcode, _ := dshandler.pluginChain.ServeDNS(ctx, w, r)
+		rcode, err := dshandler.pluginChain.ServeDNS(ctx, w, r)
 		if !plugin.ClientWrite(rcode) {
-			errorFunc(s.Addr, w, r, rcode)
+			errorFunc(s.Addr, w, r, rcode, err)
 		}
 		return
 	}
 
 	// Wildcard match, if we have found nothing try the root zone as a last resort.
 	if h, ok := s.zones["."]; ok && h.pluginChain != nil {
-		rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+		rcode, err := h.pluginChain.ServeDNS(ctx, w, r)
 		if !plugin.ClientWrite(rcode) {
-			errorFunc(s.Addr, w, r, rcode)
+			errorFunc(s.Addr, w, r, rcode, err)
 		}
 		return
 	}
@@ -313,13 +407,17 @@ func (s *Server) Tracer() ot.Tracer {
 	return s.trace.Tracer()
 }
 
-// errorFunc responds to an DNS request with an error.
-func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int) {
+// errorFunc responds to an DNS request with an error. If err carries an Extended DNS Error it is added to
+// the reply.
+func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int, err error) {
 	state := request.Request{W: w, Req: r}
 
 	answer := new(dns.Msg)
 	answer.SetRcode(r, rc)
 	state.SizeAndDo(answer)
+	if e, ok := err.(*edns.Error); ok {
+		edns.AddExtendedError(answer, e.ExtendedError)
+	}
 
 	w.WriteMsg(answer)
 }
@@ -344,6 +442,9 @@ const (
 // Key is the context key for the current server added to the context.
 type Key struct{}
 
+// InternalKey is the context key that marks lookups CoreDNS does for itself while handling a query.
+type InternalKey struct{}
+
 // EnableChaos is a map with plugin names for which we should open CH class queries as we block these by default.
 var EnableChaos = map[string]struct{}{
 	"chaos":   {},
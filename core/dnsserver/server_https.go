@@ -29,6 +29,7 @@ func NewServerHTTPS(addr string, group []*Config) (*ServerHTTPS, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.padding = true // responses over an encrypted transport should not leak their size
 	// The *tls* plugin must make sure that multiple conflicting
 	// TLS configuration return an error: it can only be specified once.
 	var tlsConfig *tls.Config
@@ -61,7 +62,11 @@ func (s *ServerHTTPS) ServePacket(p net.PacketConn) error { return nil }
 // Listen implements caddy.TCPServer interface.
 func (s *ServerHTTPS) Listen() (net.Listener, error) {
 
-	l, err := net.Listen("tcp", s.Addr[len(transport.HTTPS+"://"):])
+	addr := s.Addr[len(transport.HTTPS+"://"):]
+	if l := activatedListener(addr); l != nil {
+		return l, nil
+	}
+	l, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
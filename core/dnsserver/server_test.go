@@ -2,9 +2,12 @@ package dnsserver
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -65,3 +68,68 @@ func BenchmarkCoreServeDNS(b *testing.B) {
 		s.ServeDNS(ctx, w, m)
 	}
 }
+
+func TestExtendedErrorFromPlugin(t *testing.T) {
+	p := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		return dns.RcodeServerFailure, edns.NewError(errors.New("no healthy proxies"), edns.ExtendedErrorCodeNoReachableAuthority)
+	})
+	s, err := NewServer("127.0.0.1:53", []*Config{testConfig("dns", p)})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, m)
+
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	ede := edns.ExtendedErrors(rec.Msg)
+	if len(ede) != 1 || ede[0].InfoCode != edns.ExtendedErrorCodeNoReachableAuthority || ede[0].ExtraText != "no healthy proxies" {
+		t.Errorf("Expected No Reachable Authority extended error, got %v", ede)
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	c := testConfig("dns", plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+		return 0, nil
+	}))
+	c.MaxConcurrent = 1
+	c.ConcurrencyRcode = dns.RcodeServerFailure
+	s, err := NewServer("127.0.0.1:53", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+
+	// Pretend another query is in flight.
+	s.inflight = 1
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if s.inflight != 1 {
+		t.Errorf("Expected 1 query in flight, got %d", s.inflight)
+	}
+
+	// Internal lookups are part of the query that is already in flight.
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.WithValue(context.TODO(), InternalKey{}, true), rec, m)
+	if rec.Msg.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR for internal lookup, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+}
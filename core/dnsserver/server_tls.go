@@ -23,6 +23,7 @@ func NewServerTLS(addr string, group []*Config) (*ServerTLS, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.padding = true // responses over an encrypted transport should not leak their size
 	// The *tls* plugin must make sure that multiple conflicting
 	// TLS configuration return an error: it can only be specified once.
 	var tlsConfig *tls.Config
@@ -47,6 +48,7 @@ func (s *ServerTLS) Serve(l net.Listener) error {
 		ctx := context.Background()
 		s.ServeDNS(ctx, w, r)
 	})}
+	s.setTCPOptions(s.server[tcp])
 	s.m.Unlock()
 
 	return s.server[tcp].ActivateAndServe()
@@ -57,11 +59,15 @@ func (s *ServerTLS) ServePacket(p net.PacketConn) error { return nil }
 
 // Listen implements caddy.TCPServer interface.
 func (s *ServerTLS) Listen() (net.Listener, error) {
-	l, err := net.Listen("tcp", s.Addr[len(transport.TLS+"://"):])
+	addr := s.Addr[len(transport.TLS+"://"):]
+	if l := activatedListener(addr); l != nil {
+		return s.WrapListener(l), nil
+	}
+	l, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
-	return l, nil
+	return s.WrapListener(l), nil
 }
 
 // ListenPacket implements caddy.UDPServer interface.
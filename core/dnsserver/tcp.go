@@ -0,0 +1,130 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+
+	"github.com/miekg/dns"
+)
+
+// TCPConfig holds the limits and timeouts for TCP and DNS-over-TLS connections. Zero values keep the
+// defaults.
+type TCPConfig struct {
+	MaxConnections          int           // maximum number of open connections, 0 is unlimited
+	MaxConnectionsPerClient int           // maximum number of open connections per client address, 0 is unlimited
+	MaxQueries              int           // maximum number of queries per connection, -1 is unlimited
+	ReadTimeout             time.Duration // time to wait for the first query on a connection
+	IdleTimeout             time.Duration // time to wait for subsequent queries on a connection
+	WriteTimeout            time.Duration // time allowed to write a reply
+}
+
+// setTCPOptions sets the timeouts and the query limit from s.tcp on srv.
+func (s *Server) setTCPOptions(srv *dns.Server) {
+	if s.tcp == nil {
+		return
+	}
+	srv.ReadTimeout = s.tcp.ReadTimeout
+	if idle := s.tcp.IdleTimeout; idle > 0 {
+		srv.IdleTimeout = func() time.Duration { return idle }
+	}
+	srv.MaxTCPQueries = s.tcp.MaxQueries
+	// The dns package doesn't set write deadlines itself, limitConn does that.
+	srv.WriteTimeout = s.tcp.WriteTimeout
+}
+
+// limitListener wraps a net.Listener and closes new connections right away when there are too many
+// open connections.
+type limitListener struct {
+	net.Listener
+	server string
+	config *TCPConfig
+
+	sync.Mutex
+	open      int
+	perClient map[string]int
+}
+
+func newLimitListener(l net.Listener, server string, c *TCPConfig) *limitListener {
+	return &limitListener{Listener: l, server: server, config: c, perClient: make(map[string]int)}
+}
+
+// Accept implements the net.Listener interface.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		client, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if reason := l.acquire(client); reason != "" {
+			vars.TCPConnectionsRejected.WithLabelValues(l.server, reason).Inc()
+			conn.Close()
+			continue
+		}
+		return &limitConn{Conn: conn, l: l, client: client}, nil
+	}
+}
+
+// acquire accounts for a new connection from client. If that exceeds one of the limits, the connection
+// must be rejected and the reason is returned.
+func (l *limitListener) acquire(client string) string {
+	l.Lock()
+	defer l.Unlock()
+	if l.config.MaxConnections > 0 && l.open >= l.config.MaxConnections {
+		return "max_connections"
+	}
+	if l.config.MaxConnectionsPerClient > 0 && l.perClient[client] >= l.config.MaxConnectionsPerClient {
+		return "max_connections_per_client"
+	}
+	l.open++
+	l.perClient[client]++
+	return ""
+}
+
+func (l *limitListener) release(client string) {
+	l.Lock()
+	defer l.Unlock()
+	l.open--
+	if l.perClient[client]--; l.perClient[client] <= 0 {
+		delete(l.perClient, client)
+	}
+}
+
+// File returns a copy of the underlying file descriptor, this allows for graceful reloads.
+func (l *limitListener) File() (*os.File, error) {
+	f, ok := l.Listener.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return nil, errors.New("listener does not have a file descriptor")
+	}
+	return f.File()
+}
+
+// limitConn is a connection accepted by limitListener, closing it frees up its slot. It also applies
+// the write timeout.
+type limitConn struct {
+	net.Conn
+	l      *limitListener
+	client string
+	once   sync.Once
+}
+
+// Write implements the net.Conn interface.
+func (c *limitConn) Write(b []byte) (int, error) {
+	if t := c.l.config.WriteTimeout; t > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(t))
+	}
+	return c.Conn.Write(b)
+}
+
+// Close implements the net.Conn interface.
+func (c *limitConn) Close() error {
+	c.once.Do(func() { c.l.release(c.client) })
+	return c.Conn.Close()
+}
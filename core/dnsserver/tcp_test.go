@@ -0,0 +1,149 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func TestLimitListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	ll := newLimitListener(l, "dns://:53", &TCPConfig{MaxConnections: 2, MaxConnectionsPerClient: 1})
+	defer ll.Close()
+
+	if reason := ll.acquire("192.0.2.1"); reason != "" {
+		t.Errorf("Expected first connection to be accepted, got %s", reason)
+	}
+	if reason := ll.acquire("192.0.2.1"); reason != "max_connections_per_client" {
+		t.Errorf("Expected second connection of client to be rejected, got %q", reason)
+	}
+	if reason := ll.acquire("192.0.2.2"); reason != "" {
+		t.Errorf("Expected connection of other client to be accepted, got %s", reason)
+	}
+	if reason := ll.acquire("192.0.2.3"); reason != "max_connections" {
+		t.Errorf("Expected third connection to be rejected, got %q", reason)
+	}
+	ll.release("192.0.2.1")
+	if reason := ll.acquire("192.0.2.3"); reason != "" {
+		t.Errorf("Expected connection to be accepted after release, got %s", reason)
+	}
+	if len(ll.perClient) != 2 {
+		t.Errorf("Expected 2 clients to be tracked, got %d", len(ll.perClient))
+	}
+}
+
+func TestLimitListenerAccept(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	ll := newLimitListener(l, "dns://:53", &TCPConfig{MaxConnectionsPerClient: 1})
+	defer ll.Close()
+
+	c1, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer c1.Close()
+	s1, err := ll.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %s", err)
+	}
+
+	// The second connection is closed by the listener.
+	c2, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer c2.Close()
+	go ll.Accept()
+	c2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := c2.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Expected second connection to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Errorf("Expected second connection to be closed, got timeout")
+	}
+
+	// Closing the first one frees up the slot.
+	s1.Close()
+	s1.Close()
+	ll.Lock()
+	defer ll.Unlock()
+	if ll.open != 0 {
+		t.Errorf("Expected no open connections, got %d", ll.open)
+	}
+}
+
+func TestTCPOptions(t *testing.T) {
+	tc := &TCPConfig{MaxConnections: 1, MaxQueries: 10, ReadTimeout: time.Second, IdleTimeout: 2 * time.Second, WriteTimeout: 3 * time.Second}
+
+	s := &Server{Addr: "dns://:53", tcp: tc}
+	srv := &dns.Server{}
+	s.setTCPOptions(srv)
+	if srv.ReadTimeout != tc.ReadTimeout || srv.IdleTimeout() != tc.IdleTimeout || srv.WriteTimeout != tc.WriteTimeout {
+		t.Errorf("Expected timeouts %s, %s and %s, got %s, %s and %s", tc.ReadTimeout, tc.IdleTimeout, tc.WriteTimeout,
+			srv.ReadTimeout, srv.IdleTimeout(), srv.WriteTimeout)
+	}
+	if srv.MaxTCPQueries != tc.MaxQueries {
+		t.Errorf("Expected max queries %d, got %d", tc.MaxQueries, srv.MaxTCPQueries)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	if _, ok := s.WrapListener(l).(*limitListener); !ok {
+		t.Errorf("Expected limits on a plain DNS listener")
+	}
+	// gRPC and HTTPS servers handle their connections themselves.
+	s.Addr = "grpc://:443"
+	if _, ok := s.WrapListener(l).(*limitListener); ok {
+		t.Errorf("Expected no limits on a gRPC listener")
+	}
+}
+
+func TestTCPOptionsTLS(t *testing.T) {
+	c := testConfig("tls", plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		return 0, nil
+	}))
+	c.TCP = &TCPConfig{MaxConnections: 1, MaxQueries: 10}
+	s, err := NewServerTLS("tls://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServerTLS, got %s", err)
+	}
+
+	l, err := s.Listen()
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	if _, ok := l.(*limitListener); !ok {
+		t.Fatalf("Expected limits on a DNS-over-TLS listener")
+	}
+
+	go s.Serve(l)
+	defer s.Stop()
+
+	for i := 0; i < 100; i++ {
+		s.m.Lock()
+		srv := s.server[tcp]
+		s.m.Unlock()
+		if srv != nil {
+			if srv.MaxTCPQueries != 10 {
+				t.Errorf("Expected max queries %d, got %d", 10, srv.MaxTCPQueries)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timeout waiting for the DNS-over-TLS server to start")
+}
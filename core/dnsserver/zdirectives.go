@@ -17,6 +17,11 @@ var Directives = []string{
 	"nsid",
 	"root",
 	"bind",
+	"proxyproto",
+	"tcp",
+	"cookie",
+	"concurrency",
+	"reuseport",
 	"debug",
 	"trace",
 	"ready",
@@ -29,19 +34,24 @@ var Directives = []string{
 	"chaos",
 	"loadbalance",
 	"cache",
+	"dns64",
 	"rewrite",
 	"dnssec",
 	"autopath",
 	"template",
 	"hosts",
 	"route53",
+	"azure",
+	"clouddns",
 	"federation",
+	"alias",
 	"k8s_external",
 	"kubernetes",
 	"file",
 	"auto",
 	"secondary",
 	"etcd",
+	"consul",
 	"loop",
 	"forward",
 	"grpc",
@@ -0,0 +1,149 @@
+/*
+Package alias implements a plugin that aliases names to another domain, typically the domain of
+another cluster. A query matching a rule is answered with a CNAME to the rewritten name when the
+name isn't available locally: the rest of the chain returns NXDOMAIN, or a plugin implementing
+Endpointer reports there are no local endpoints for it.
+
+This replaces the federation plugin: instead of relying on the federation v1 naming scheme any
+name can be mapped to any other domain.
+*/
+package alias
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Alias is the alias plugin.
+type Alias struct {
+	Next     plugin.Handler
+	Zones    []string
+	Rules    []Rule
+	Upstream Upstream // if not nil, used to resolve the target of the CNAME
+
+	ttl       uint32
+	endpoints Endpointer
+}
+
+// Upstream is used to resolve the target of the CNAME.
+type Upstream interface {
+	Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
+}
+
+// Endpointer is implemented by plugins that know if a name has local endpoints, the
+// kubernetes plugin implements it.
+type Endpointer interface {
+	// HasEndpoints returns false if the name in state is known to have no local endpoints.
+	HasEndpoints(state request.Request) bool
+}
+
+// Rule maps names matching a regular expression to a target.
+type Rule struct {
+	regex  *regexp.Regexp
+	target string // expanded with the submatches of regex
+	when   condition
+}
+
+// condition determines when a rule aliases a name.
+type condition int
+
+const (
+	// unavailable aliases when the name does not exist locally, or has no local endpoints.
+	unavailable condition = iota
+	// nxdomain aliases only when the name does not exist locally.
+	nxdomain
+	// always aliases unconditionally.
+	always
+)
+
+var conditions = map[string]condition{
+	"unavailable": unavailable,
+	"nxdomain":    nxdomain,
+	"always":      always,
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (a *Alias) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(a.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+
+	rule, target := a.match(state.Name())
+	if rule == nil {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+
+	if rule.when != always {
+		// Start the next plugin, but with a nonwriter, and only alias if the name isn't available.
+		nw := nonwriter.New(w)
+		rcode, err := plugin.NextOrFailure(a.Name(), a.Next, ctx, nw, r)
+		// Nothing to look at when the next plugin didn't write a reply (or it failed to).
+		if !plugin.ClientWrite(rcode) || nw.Msg == nil {
+			return rcode, err
+		}
+		if !a.unavailable(rule.when, state, nw.Msg) {
+			w.WriteMsg(nw.Msg)
+			return rcode, err
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	m.Answer = []dns.RR{&dns.CNAME{
+		Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: a.ttl},
+		Target: target,
+	}}
+
+	if a.Upstream != nil && state.QType() != dns.TypeCNAME {
+		up, err := a.Upstream.Lookup(ctx, state, target, state.QType())
+		if err == nil && up != nil {
+			m.Answer = append(m.Answer, up.Answer...)
+		}
+	}
+
+	aliasCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+
+	state.SizeAndDo(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (a *Alias) Name() string { return "alias" }
+
+// match returns the first rule matching name and the target name is aliased to. If no rule
+// matches, nil is returned.
+func (a *Alias) match(name string) (*Rule, string) {
+	for i, r := range a.Rules {
+		sub := r.regex.FindStringSubmatchIndex(name)
+		if sub == nil {
+			continue
+		}
+		target := r.regex.ExpandString(nil, r.target, name, sub)
+		return &a.Rules[i], dns.Fqdn(string(target))
+	}
+	return nil, ""
+}
+
+// unavailable returns true if the local reply m for the query in state means the name should
+// be aliased.
+func (a *Alias) unavailable(when condition, state request.Request, m *dns.Msg) bool {
+	if m.Rcode == dns.RcodeNameError {
+		return true
+	}
+	if when == nxdomain || m.Rcode != dns.RcodeSuccess || a.endpoints == nil {
+		return false
+	}
+	return !a.endpoints.HasEndpoints(state)
+}
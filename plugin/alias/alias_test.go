@@ -0,0 +1,151 @@
+package alias
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+type fakeUpstream map[string][]dns.RR
+
+func (f fakeUpstream) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, typ)
+	m.Answer = f[name]
+	return m, nil
+}
+
+// noEndpoints reports no endpoints for the names in it.
+type noEndpoints map[string]bool
+
+func (n noEndpoints) HasEndpoints(state request.Request) bool { return !n[state.Name()] }
+
+// next has a.svc.cluster.local. and b.svc.cluster.local., other names are NXDOMAIN.
+func next() test.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "a.svc.cluster.local.", "b.svc.cluster.local.":
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 5 IN A 10.0.0.1")}
+		default:
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+}
+
+func TestAlias(t *testing.T) {
+	a := &Alias{
+		Next:  next(),
+		Zones: []string{"cluster.local."},
+		Rules: []Rule{
+			{regex: regexp.MustCompile(`^static[.]cluster[.]local[.]$`), target: "static.example.org", when: always},
+			{regex: regexp.MustCompile(`^(.*)[.]svc[.]cluster[.]local[.]$`), target: "$1.svc.west.example.org", when: unavailable},
+		},
+		Upstream: fakeUpstream{
+			"a.svc.west.example.org.": {test.A("a.svc.west.example.org. 30 IN A 10.1.0.1")},
+			"c.svc.west.example.org.": {test.A("c.svc.west.example.org. 30 IN A 10.1.0.3")},
+		},
+		ttl:       defaultTTL,
+		endpoints: noEndpoints{"a.svc.cluster.local.": true},
+	}
+
+	tests := []test.Case{
+		{
+			// Available locally.
+			Qname: "b.svc.cluster.local.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("b.svc.cluster.local. 5 IN A 10.0.0.1")},
+		},
+		{
+			// No local endpoints.
+			Qname: "a.svc.cluster.local.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("a.svc.cluster.local. 5 IN CNAME a.svc.west.example.org."),
+				test.A("a.svc.west.example.org. 30 IN A 10.1.0.1"),
+			},
+		},
+		{
+			// Doesn't exist locally.
+			Qname: "c.svc.cluster.local.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("c.svc.cluster.local. 5 IN CNAME c.svc.west.example.org."),
+				test.A("c.svc.west.example.org. 30 IN A 10.1.0.3"),
+			},
+		},
+		{
+			Qname: "static.cluster.local.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.CNAME("static.cluster.local. 5 IN CNAME static.example.org.")},
+		},
+		{
+			// No rule matches.
+			Qname: "other.cluster.local.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := a.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestAliasNXDOMAIN(t *testing.T) {
+	a := &Alias{
+		Next:      next(),
+		Zones:     []string{"cluster.local."},
+		Rules:     []Rule{{regex: regexp.MustCompile(`^(.*)[.]svc[.]cluster[.]local[.]$`), target: "$1.svc.west.example.org", when: nxdomain}},
+		ttl:       defaultTTL,
+		endpoints: noEndpoints{"a.svc.cluster.local.": true},
+	}
+
+	for _, qname := range []string{"a.svc.cluster.local.", "c.svc.cluster.local."} {
+		m := new(dns.Msg)
+		m.SetQuestion(qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		a.ServeDNS(context.TODO(), rec, m)
+
+		_, aliased := rec.Msg.Answer[0].(*dns.CNAME)
+		if expect := qname == "c.svc.cluster.local."; aliased != expect {
+			t.Errorf("Expected aliased to be %t for %s, got %t", expect, qname, aliased)
+		}
+	}
+}
+
+func TestAliasNoReply(t *testing.T) {
+	// The next plugin returns success, but doesn't write a reply.
+	a := &Alias{
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			return dns.RcodeSuccess, nil
+		}),
+		Zones: []string{"cluster.local."},
+		Rules: []Rule{{regex: regexp.MustCompile(`^(.*)[.]svc[.]cluster[.]local[.]$`), target: "$1.svc.west.example.org"}},
+		ttl:   defaultTTL,
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("a.svc.cluster.local.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, err := a.ServeDNS(context.TODO(), rec, m)
+	if rcode != dns.RcodeSuccess || err != nil {
+		t.Errorf("Expected rcode %d and no error, got %d and %v", dns.RcodeSuccess, rcode, err)
+	}
+	if rec.Msg != nil {
+		t.Errorf("Expected no reply to be written, got %v", rec.Msg)
+	}
+}
@@ -0,0 +1,15 @@
+package alias
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// aliasCount is the number of queries answered with an alias.
+var aliasCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "alias",
+	Name:      "aliased_total",
+	Help:      "Counter of queries answered with a CNAME to another domain.",
+}, []string{"server", "zone"})
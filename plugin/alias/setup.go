@@ -0,0 +1,135 @@
+package alias
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("alias", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	a, ep, err := aliasParse(c)
+	if err != nil {
+		return plugin.Error("alias", err)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, aliasCount)
+		return nil
+	})
+
+	if ep != "" {
+		// Do this in OnStartup, so all plugins have been initialized.
+		c.OnStartup(func() error {
+			m := dnsserver.GetConfig(c).Handler(ep)
+			if m == nil {
+				return plugin.Error("alias", fmt.Errorf("no plugin %s configured", ep))
+			}
+			x, ok := m.(Endpointer)
+			if !ok {
+				return plugin.Error("alias", fmt.Errorf("%s does not implement the Endpointer interface", ep))
+			}
+			a.endpoints = x
+			return nil
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		a.Next = next
+		return a
+	})
+
+	return nil
+}
+
+func aliasParse(c *caddy.Controller) (*Alias, string, error) {
+	a := &Alias{ttl: defaultTTL}
+	ep := ""
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, "", plugin.ErrOnce
+		}
+		i++
+
+		a.Zones = c.RemainingArgs()
+		if len(a.Zones) == 0 {
+			a.Zones = make([]string, len(c.ServerBlockKeys))
+			copy(a.Zones, c.ServerBlockKeys)
+		}
+		for i, str := range a.Zones {
+			a.Zones[i] = plugin.Host(str).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "rule":
+				args := c.RemainingArgs()
+				if len(args) < 2 || len(args) > 3 {
+					return nil, "", c.ArgErr()
+				}
+				regex, err := regexp.Compile(args[0])
+				if err != nil {
+					return nil, "", c.Errf("could not parse regex: %s, %v", args[0], err)
+				}
+				r := Rule{regex: regex, target: args[1]}
+				if len(args) == 3 {
+					when, ok := conditions[args[2]]
+					if !ok {
+						return nil, "", c.Errf("unknown condition %q", args[2])
+					}
+					r.when = when
+				}
+				a.Rules = append(a.Rules, r)
+			case "endpoints":
+				if !c.NextArg() {
+					return nil, "", c.ArgErr()
+				}
+				ep = c.Val()
+				if c.NextArg() {
+					return nil, "", c.ArgErr()
+				}
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, "", c.ArgErr()
+				}
+				t, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, "", err
+				}
+				if t < 0 || t > 3600 {
+					return nil, "", c.Errf("ttl must be in range [0, 3600]: %d", t)
+				}
+				a.ttl = uint32(t)
+			case "upstream":
+				c.RemainingArgs() // eat remaining args
+				a.Upstream = upstream.New()
+			default:
+				return nil, "", c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(a.Rules) == 0 {
+		return nil, "", fmt.Errorf("no rules specified")
+	}
+	return a, ep, nil
+}
+
+// defaultTTL is the TTL of the CNAME, it's low because aliasing depends on local availability.
+const defaultTTL = 5
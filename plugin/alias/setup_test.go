@@ -0,0 +1,75 @@
+package alias
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rules     int
+		endpoints string
+		ttl       uint32
+	}{
+		{`alias {
+			rule ^(.*)[.]svc[.]cluster[.]local[.]$ $1.svc.west.example.org
+		}`, false, 1, "", defaultTTL},
+		{`alias cluster.local {
+			rule ^(.*)[.]svc[.]cluster[.]local[.]$ $1.svc.west.example.org unavailable
+			rule ^static[.]cluster[.]local[.]$ static.example.org always
+			endpoints kubernetes
+			ttl 30
+			upstream
+		}`, false, 2, "kubernetes", 30},
+		// fails
+		{`alias`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*$ $1.example.org
+		}`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*)$
+		}`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*)$ $1.example.org sometimes
+		}`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*)$ $1.example.org
+			endpoints
+		}`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*)$ $1.example.org
+			ttl -1
+		}`, true, 0, "", 0},
+		{`alias {
+			rule ^(.*)$ $1.example.org
+			foo
+		}`, true, 0, "", 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		a, ep, err := aliasParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+		if len(a.Rules) != test.rules {
+			t.Errorf("Test %d: expected %d rules, got %d", i, test.rules, len(a.Rules))
+		}
+		if ep != test.endpoints {
+			t.Errorf("Test %d: expected endpoints plugin %q, got %q", i, test.endpoints, ep)
+		}
+		if a.ttl != test.ttl {
+			t.Errorf("Test %d: expected ttl %d, got %d", i, test.ttl, a.ttl)
+		}
+	}
+}
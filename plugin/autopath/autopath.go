@@ -28,11 +28,15 @@ I.e:
 func (m Plugins ) AutoPath(state request.Request) []string {
 	return []string{"first", "second", "last", ""}
 }
+
+Search paths can also be configured per client network, these take precedence over
+the search path from the resolv.conf file or the plugin.
 */
 package autopath
 
 import (
 	"context"
+	"net"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
@@ -62,6 +66,15 @@ type AutoPath struct {
 	// Search always includes "" as the last element, so we try the base query with out any search paths added as well.
 	search     []string
 	searchFunc Func
+
+	// clients holds search paths per client network, sorted from most to least specific.
+	clients []client
+}
+
+// client is a search path for the clients in net.
+type client struct {
+	net    *net.IPNet
+	search []string
 }
 
 // ServeDNS implements the plugin.Handle interface.
@@ -73,12 +86,16 @@ func (a *AutoPath) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
 	}
 
-	// Check if autopath should be done, searchFunc takes precedence over the local configured search path.
+	// Check if autopath should be done, the client's network takes precedence over searchFunc, which
+	// takes precedence over the local configured search path.
 	var err error
-	searchpath := a.search
+	searchpath := a.clientSearch(state.IP())
 
-	if a.searchFunc != nil {
-		searchpath = a.searchFunc(state)
+	if searchpath == nil {
+		searchpath = a.search
+		if a.searchFunc != nil {
+			searchpath = a.searchFunc(state)
+		}
 	}
 
 	if len(searchpath) == 0 {
@@ -134,6 +151,8 @@ func (a *AutoPath) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		// Write whatever non-nxdomain answer we've found.
 		w.WriteMsg(msg)
 		autoPathCount.WithLabelValues(metrics.WithServer(ctx)).Add(1)
+		// Without us the client would have sent a query for each of the elements we skipped.
+		autoPathSaved.WithLabelValues(metrics.WithServer(ctx)).Add(float64(i))
 		return rcode, err
 
 	}
@@ -146,6 +165,44 @@ func (a *AutoPath) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 // Name implements the Handler interface.
 func (a *AutoPath) Name() string { return "autopath" }
 
+// clientSearch returns the search path configured for the network of the client with address ip,
+// or nil if there is none.
+func (a *AutoPath) clientSearch(ip string) []string {
+	if len(a.clients) == 0 {
+		return nil
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil
+	}
+	for _, c := range a.clients {
+		if c.net.Contains(addr) {
+			return c.search
+		}
+	}
+	return nil
+}
+
+// SearchPath returns a search path for a client with the name host: the parent domains of host
+// up to and including zone, followed by the empty string. This mimics the search path a
+// resolver derives from its own domain name. If host is not in zone nil is returned.
+// Plugins that know the name of the client can use this to implement AutoPather.
+func SearchPath(host, zone string) []string {
+	host = dns.Fqdn(host)
+	if !dns.IsSubDomain(zone, host) || host == zone {
+		return nil
+	}
+
+	search := []string{}
+	for _, off := range dns.Split(host)[1:] {
+		if !dns.IsSubDomain(zone, host[off:]) {
+			break
+		}
+		search = append(search, host[off:])
+	}
+	return append(search, "") // sentinel
+}
+
 // firstInSearchPath checks if name is equal to are a sibling of the first element in the search path.
 func firstInSearchPath(name string, searchpath []string) bool {
 	if name == searchpath[0] {
@@ -2,6 +2,8 @@ package autopath
 
 import (
 	"context"
+	"net"
+	"reflect"
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
@@ -164,3 +166,44 @@ func TestInSearchPath(t *testing.T) {
 		}
 	}
 }
+
+func TestAutoPathClient(t *testing.T) {
+	ap := newTestAutoPath()
+	_, n, _ := net.ParseCIDR("10.240.0.0/24")
+	ap.clients = []client{{net: n, search: []string{"example.org.", "net.", ""}}}
+	ap.Next = nextHandler(map[string]int{
+		"b.example.org.": dns.RcodeNameError,
+		"b.com.":         dns.RcodeSuccess,
+		"b.net.":         dns.RcodeSuccess,
+	})
+
+	// test.ResponseWriter uses 10.240.0.1 as the client address.
+	m := new(dns.Msg)
+	m.SetQuestion("b.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := ap.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rec.Msg.Answer) != 2 || rec.Msg.Answer[1].Header().Name != "b.net." {
+		t.Errorf("Expected answer for b.net., got %v", rec.Msg.Answer)
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	tests := []struct {
+		host   string
+		zone   string
+		search []string
+	}{
+		{"web.prod.example.org.", "example.org.", []string{"prod.example.org.", "example.org.", ""}},
+		{"web.example.org", "example.org.", []string{"example.org.", ""}},
+		{"web.example.org.", ".", []string{"example.org.", "org.", ""}},
+		{"example.org.", "example.org.", nil},
+		{"web.example.net.", "example.org.", nil},
+	}
+	for i, tc := range tests {
+		if got := SearchPath(tc.host, tc.zone); !reflect.DeepEqual(got, tc.search) {
+			t.Errorf("Test %d, expected %v, got %v", i, tc.search, got)
+		}
+	}
+}
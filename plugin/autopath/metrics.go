@@ -13,4 +13,11 @@ var (
 		Name:      "success_count_total",
 		Help:      "Counter of requests that did autopath.",
 	}, []string{"server"})
+
+	autoPathSaved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "autopath",
+		Name:      "round_trips_saved_total",
+		Help:      "Counter of queries clients did not have to send because of autopath.",
+	}, []string{"server"})
 )
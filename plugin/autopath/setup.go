@@ -2,6 +2,8 @@ package autopath
 
 import (
 	"fmt"
+	"net"
+	"sort"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -26,7 +28,7 @@ func setup(c *caddy.Controller) error {
 	}
 
 	c.OnStartup(func() error {
-		metrics.MustRegister(c, autoPathCount)
+		metrics.MustRegister(c, autoPathCount, autoPathSaved)
 		return nil
 	})
 
@@ -58,23 +60,54 @@ func autoPathParse(c *caddy.Controller) (*AutoPath, string, error) {
 
 	for c.Next() {
 		zoneAndresolv := c.RemainingArgs()
-		if len(zoneAndresolv) < 1 {
-			return ap, "", fmt.Errorf("no resolv-conf specified")
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "client":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return ap, "", c.ArgErr()
+				}
+				_, n, err := net.ParseCIDR(args[0])
+				if err != nil {
+					return ap, "", err
+				}
+				search := args[1:]
+				plugin.Zones(search).Normalize()
+				search = append(search, "") // sentinel value as demanded.
+				ap.clients = append(ap.clients, client{net: n, search: search})
+			default:
+				return ap, "", c.Errf("unknown property '%s'", c.Val())
+			}
 		}
-		resolv := zoneAndresolv[len(zoneAndresolv)-1]
-		if resolv[0] == '@' {
-			mw = resolv[1:]
+
+		// The last argument is the resolv-conf. With a client table it may be "none", or be left out
+		// when no zones are given either.
+		if len(zoneAndresolv) == 0 {
+			if len(ap.clients) == 0 {
+				return ap, "", fmt.Errorf("no resolv-conf specified")
+			}
 		} else {
-			// assume file on disk
-			rc, err := dns.ClientConfigFromFile(resolv)
-			if err != nil {
-				return ap, "", fmt.Errorf("failed to parse %q: %v", resolv, err)
+			resolv := zoneAndresolv[len(zoneAndresolv)-1]
+			switch {
+			case resolv == noResolv:
+				if len(ap.clients) == 0 {
+					return ap, "", fmt.Errorf("resolv-conf %q needs a client table", noResolv)
+				}
+			case resolv[0] == '@':
+				mw = resolv[1:]
+			default:
+				// assume file on disk
+				rc, err := dns.ClientConfigFromFile(resolv)
+				if err != nil {
+					return ap, "", fmt.Errorf("failed to parse %q: %v", resolv, err)
+				}
+				ap.search = rc.Search
+				plugin.Zones(ap.search).Normalize()
+				ap.search = append(ap.search, "") // sentinel value as demanded.
 			}
-			ap.search = rc.Search
-			plugin.Zones(ap.search).Normalize()
-			ap.search = append(ap.search, "") // sentinel value as demanded.
+			ap.Zones = zoneAndresolv[:len(zoneAndresolv)-1]
 		}
-		ap.Zones = zoneAndresolv[:len(zoneAndresolv)-1]
 		if len(ap.Zones) == 0 {
 			ap.Zones = make([]string, len(c.ServerBlockKeys))
 			copy(ap.Zones, c.ServerBlockKeys)
@@ -83,5 +116,15 @@ func autoPathParse(c *caddy.Controller) (*AutoPath, string, error) {
 			ap.Zones[i] = plugin.Host(str).Normalize()
 		}
 	}
+
+	// Most specific network first.
+	sort.SliceStable(ap.clients, func(i, j int) bool {
+		oi, _ := ap.clients[i].net.Mask.Size()
+		oj, _ := ap.clients[j].net.Mask.Size()
+		return oi > oj
+	})
 	return ap, mw, nil
 }
+
+// noResolv is the resolv-conf for using only the client table.
+const noResolv = "none"
@@ -31,8 +31,39 @@ func TestSetupAutoPath(t *testing.T) {
 		{`autopath example.org @kubernetes`, false, "example.org.", "kubernetes", nil, ""},
 		{`autopath 10.0.0.0/8 @kubernetes`, false, "10.in-addr.arpa.", "kubernetes", nil, ""},
 		{`autopath ` + resolv, false, "", "", []string{"bar.com.", "baz.com.", ""}, ""},
+		{`autopath {
+			client 10.0.0.0/8 example.org
+		}`, false, "", "", nil, ""},
+		{`autopath example.org none {
+			client 10.0.0.0/8 example.org
+		}`, false, "example.org.", "", nil, ""},
+		{`autopath none {
+			client 10.0.0.0/8 example.org
+		}`, false, "", "", nil, ""},
+		{`autopath example.org ` + resolv + ` {
+			client 10.0.0.0/8 example.org
+		}`, false, "example.org.", "", []string{"bar.com.", "baz.com.", ""}, ""},
+		{`autopath @etcd {
+			client 10.0.0.0/8 example.org
+		}`, false, "", "etcd", nil, ""},
 		// negative
+		{`autopath {
+			client 10.0.0.0/8
+		}`, true, "", "", nil, "Wrong argument count"},
+		{`autopath {
+			client 10.0.0.0 example.org
+		}`, true, "", "", nil, "invalid CIDR address"},
+		{`autopath {
+			foo
+		}`, true, "", "", nil, "unknown property"},
 		{`autopath kubernetes`, true, "", "", nil, "open kubernetes: no such file or directory"},
+		{`autopath example.org {
+			client 10.0.0.0/8 example.org
+		}`, true, "", "", nil, "open example.org: no such file or directory"},
+		{`autopath example.org /etc/resolv.conf.typo {
+			client 10.0.0.0/8 example.org
+		}`, true, "", "", nil, "no such file or directory"},
+		{`autopath none`, true, "", "", nil, "needs a client table"},
 		{`autopath`, true, "", "", nil, "no resolv-conf"},
 	}
 
@@ -70,6 +101,33 @@ func TestSetupAutoPath(t *testing.T) {
 	}
 }
 
+func TestSetupAutoPathClients(t *testing.T) {
+	c := caddy.NewTestController("dns", `autopath {
+		client 10.0.0.0/8 example.org
+		client 10.1.0.0/16 a.example.org example.org
+		client 2001:db8::/32 example.net
+	}`)
+	ap, _, err := autoPathParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		ip     string
+		search []string
+	}{
+		{"10.0.0.1", []string{"example.org.", ""}},
+		{"10.1.0.1", []string{"a.example.org.", "example.org.", ""}},
+		{"2001:db8::1", []string{"example.net.", ""}},
+		{"192.168.0.1", nil},
+	}
+	for i, tc := range tests {
+		if got := ap.clientSearch(tc.ip); !reflect.DeepEqual(got, tc.search) {
+			t.Errorf("Test %d, expected search path %v for %s, got %v", i, tc.search, tc.ip, got)
+		}
+	}
+}
+
 const resolvConf = `nameserver 1.2.3.4
 domain foo.com
 search bar.com baz.com
@@ -0,0 +1,300 @@
+// Package azure implements a plugin that returns resource records
+// from Azure DNS.
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	azuredns "github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	"github.com/miekg/dns"
+)
+
+// Azure is a plugin that returns RR from Azure DNS.
+type Azure struct {
+	Next plugin.Handler
+	Fall fall.F
+
+	zoneNames []string
+	client    Client
+	upstream  *upstream.Upstream
+	refresh   time.Duration
+
+	zMu   sync.RWMutex
+	zones zones
+}
+
+type zone struct {
+	resourceGroup string
+	z             *file.Zone
+	dns           string
+}
+
+type zones map[string][]*zone
+
+// New reads from the keys map which uses domain names as its key and resource
+// group lists as its values, validates that each domain name/resource group pair
+// does exist, and returns a new *Azure. In addition to this, upstream is passed
+// for doing recursive queries against CNAMEs.
+// Returns error if it cannot verify any given domain name/resource group pair.
+func New(ctx context.Context, c Client, keys map[string][]string, up *upstream.Upstream) (*Azure, error) {
+	zones := make(map[string][]*zone, len(keys))
+	zoneNames := make([]string, 0, len(keys))
+	for dns, resourceGroups := range keys {
+		for _, resourceGroup := range resourceGroups {
+			if err := c.GetZone(ctx, resourceGroup, strings.TrimSuffix(dns, ".")); err != nil {
+				return nil, err
+			}
+			if _, ok := zones[dns]; !ok {
+				zoneNames = append(zoneNames, dns)
+			}
+			zones[dns] = append(zones[dns], &zone{resourceGroup: resourceGroup, dns: dns, z: file.NewZone(dns, "")})
+		}
+	}
+	return &Azure{
+		client:    c,
+		zoneNames: zoneNames,
+		zones:     zones,
+		upstream:  up,
+		refresh:   defaultRefresh,
+	}, nil
+}
+
+// Run executes first update, spins up an update forever-loop.
+// Returns error if first update fails.
+func (h *Azure) Run(ctx context.Context) error {
+	if err := h.updateZones(ctx); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				log.Infof("Breaking out of Azure update loop: %v", ctx.Err())
+				return
+			case <-time.After(h.refresh):
+				if err := h.updateZones(ctx); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
+					log.Errorf("Failed to update zones: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// ServeDNS implements the plugin.Handler.ServeDNS.
+func (h *Azure) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	zName := plugin.Zones(h.zoneNames).Matches(qname)
+	if zName == "" {
+		return plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+	}
+	z, ok := h.zones[zName]
+	if !ok || z == nil {
+		return dns.RcodeServerFailure, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	var result file.Result
+	for _, resourceGroupZone := range z {
+		h.zMu.RLock()
+		m.Answer, m.Ns, m.Extra, result = resourceGroupZone.z.Lookup(ctx, state, qname)
+		h.zMu.RUnlock()
+
+		// Take the answer if it's non-empty OR if there is another
+		// record type exists for this name (NODATA).
+		if len(m.Answer) != 0 || result == file.NoData {
+			break
+		}
+	}
+
+	if len(m.Answer) == 0 && result != file.NoData && h.Fall.Through(qname) {
+		return plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+	}
+
+	switch result {
+	case file.Success:
+	case file.NoData:
+	case file.NameError:
+		m.Rcode = dns.RcodeNameError
+	case file.Delegation:
+		m.Authoritative = false
+	case file.ServerFailure:
+		return dns.RcodeServerFailure, nil
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// recordsFromRecordSet returns the records in the Azure record set rs.
+func recordsFromRecordSet(rs azuredns.RecordSet) ([]dns.RR, error) {
+	p := rs.RecordSetProperties
+	if p == nil || p.Fqdn == nil {
+		return nil, fmt.Errorf("record set %q has no properties", stringValue(rs.Name))
+	}
+	hdr := func(t uint16) dns.RR_Header {
+		return dns.RR_Header{Name: dns.Fqdn(*p.Fqdn), Rrtype: t, Class: dns.ClassINET, Ttl: uint32(int64Value(p.TTL))}
+	}
+
+	var rrs []dns.RR
+	if p.ARecords != nil {
+		for _, a := range *p.ARecords {
+			ip := net.ParseIP(stringValue(a.Ipv4Address)).To4()
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IPv4 address %q in %s", stringValue(a.Ipv4Address), *p.Fqdn)
+			}
+			rrs = append(rrs, &dns.A{Hdr: hdr(dns.TypeA), A: ip})
+		}
+	}
+	if p.AaaaRecords != nil {
+		for _, a := range *p.AaaaRecords {
+			ip := net.ParseIP(stringValue(a.Ipv6Address))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IPv6 address %q in %s", stringValue(a.Ipv6Address), *p.Fqdn)
+			}
+			rrs = append(rrs, &dns.AAAA{Hdr: hdr(dns.TypeAAAA), AAAA: ip})
+		}
+	}
+	if p.MxRecords != nil {
+		for _, mx := range *p.MxRecords {
+			rrs = append(rrs, &dns.MX{Hdr: hdr(dns.TypeMX), Preference: uint16(int32Value(mx.Preference)), Mx: dns.Fqdn(stringValue(mx.Exchange))})
+		}
+	}
+	if p.NsRecords != nil {
+		for _, ns := range *p.NsRecords {
+			rrs = append(rrs, &dns.NS{Hdr: hdr(dns.TypeNS), Ns: dns.Fqdn(stringValue(ns.Nsdname))})
+		}
+	}
+	if p.PtrRecords != nil {
+		for _, ptr := range *p.PtrRecords {
+			rrs = append(rrs, &dns.PTR{Hdr: hdr(dns.TypePTR), Ptr: dns.Fqdn(stringValue(ptr.Ptrdname))})
+		}
+	}
+	if p.SrvRecords != nil {
+		for _, srv := range *p.SrvRecords {
+			rrs = append(rrs, &dns.SRV{Hdr: hdr(dns.TypeSRV), Priority: uint16(int32Value(srv.Priority)), Weight: uint16(int32Value(srv.Weight)),
+				Port: uint16(int32Value(srv.Port)), Target: dns.Fqdn(stringValue(srv.Target))})
+		}
+	}
+	if p.TxtRecords != nil {
+		for _, txt := range *p.TxtRecords {
+			if txt.Value == nil {
+				continue
+			}
+			rrs = append(rrs, &dns.TXT{Hdr: hdr(dns.TypeTXT), Txt: *txt.Value})
+		}
+	}
+	if p.CaaRecords != nil {
+		for _, caa := range *p.CaaRecords {
+			rrs = append(rrs, &dns.CAA{Hdr: hdr(dns.TypeCAA), Flag: uint8(int32Value(caa.Flags)), Tag: stringValue(caa.Tag), Value: stringValue(caa.Value)})
+		}
+	}
+	if p.CnameRecord != nil {
+		rrs = append(rrs, &dns.CNAME{Hdr: hdr(dns.TypeCNAME), Target: dns.Fqdn(stringValue(p.CnameRecord.Cname))})
+	}
+	if soa := p.SoaRecord; soa != nil {
+		rrs = append(rrs, &dns.SOA{Hdr: hdr(dns.TypeSOA), Ns: dns.Fqdn(stringValue(soa.Host)), Mbox: dns.Fqdn(stringValue(soa.Email)),
+			Serial: uint32(int64Value(soa.SerialNumber)), Refresh: uint32(int64Value(soa.RefreshTime)), Retry: uint32(int64Value(soa.RetryTime)),
+			Expire: uint32(int64Value(soa.ExpireTime)), Minttl: uint32(int64Value(soa.MinimumTTL))})
+	}
+	return rrs, nil
+}
+
+// updateZones re-queries record sets for each zone and updates the
+// zone object.
+// Returns error if any zones error'ed out, but waits for other zones to
+// complete first.
+func (h *Azure) updateZones(ctx context.Context) error {
+	errc := make(chan error)
+	defer close(errc)
+	for zName, z := range h.zones {
+		go func(zName string, z []*zone) {
+			var err error
+			defer func() {
+				errc <- err
+			}()
+
+			for i, resourceGroupZone := range z {
+				newZ := file.NewZone(zName, "")
+				newZ.Upstream = h.upstream
+
+				var sets []azuredns.RecordSet
+				sets, err = h.client.ListRecordSets(ctx, resourceGroupZone.resourceGroup, strings.TrimSuffix(zName, "."))
+				if err != nil {
+					err = fmt.Errorf("failed to list record sets for %v:%v from azure: %v", resourceGroupZone.resourceGroup, zName, err)
+					return
+				}
+				for _, rs := range sets {
+					rrs, err := recordsFromRecordSet(rs)
+					if err != nil {
+						// Maybe unsupported record type. Log and carry on.
+						log.Warningf("Failed to process record set: %v", err)
+						continue
+					}
+					for _, rr := range rrs {
+						newZ.Insert(rr)
+					}
+				}
+
+				h.zMu.Lock()
+				(*z[i]).z = newZ
+				h.zMu.Unlock()
+			}
+
+		}(zName, z)
+	}
+	// Collect errors (if any). This will also sync on all zones updates
+	// completion.
+	var errs []string
+	for i := 0; i < len(h.zones); i++ {
+		err := <-errc
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("errors updating zones: %v", errs)
+	}
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func int32Value(i *int32) int32 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func int64Value(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+const defaultRefresh = 1 * time.Minute
+
+// Name implements plugin.Handler.Name.
+func (h *Azure) Name() string { return "azure" }
@@ -0,0 +1,156 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/test"
+	crequest "github.com/coredns/coredns/request"
+
+	azuredns "github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	"github.com/miekg/dns"
+)
+
+type fakeAzure struct{}
+
+func (fakeAzure) GetZone(_ context.Context, resourceGroup, zone string) error {
+	if resourceGroup == "nosuch" {
+		return errors.New("zone not found")
+	}
+	return nil
+}
+
+func str(s string) *string { return &s }
+func i32(i int32) *int32   { return &i }
+func i64(i int64) *int64   { return &i }
+
+func recordSet(fqdn string, p azuredns.RecordSetProperties) azuredns.RecordSet {
+	p.Fqdn = str(fqdn)
+	p.TTL = i64(300)
+	return azuredns.RecordSet{Name: str(fqdn), RecordSetProperties: &p}
+}
+
+func (fakeAzure) ListRecordSets(_ context.Context, resourceGroup, zone string) ([]azuredns.RecordSet, error) {
+	if resourceGroup == "bad" {
+		return nil, errors.New("bad. zone is bad")
+	}
+	soa := recordSet(dns.Fqdn(zone), azuredns.RecordSetProperties{SoaRecord: &azuredns.SoaRecord{
+		Host: str("ns1-03.azure-dns.com."), Email: str("azuredns-hostmaster.microsoft.com"), SerialNumber: i64(1),
+		RefreshTime: i64(3600), RetryTime: i64(300), ExpireTime: i64(2419200), MinimumTTL: i64(300),
+	}})
+	sets := map[string][]azuredns.RecordSet{
+		"rg1": {
+			soa,
+			recordSet("example.org.", azuredns.RecordSetProperties{ARecords: &[]azuredns.ARecord{{Ipv4Address: str("1.2.3.4")}}}),
+			recordSet("example.org.", azuredns.RecordSetProperties{AaaaRecords: &[]azuredns.AaaaRecord{{Ipv6Address: str("2001:db8::1")}}}),
+			recordSet("example.org.", azuredns.RecordSetProperties{MxRecords: &[]azuredns.MxRecord{{Preference: i32(10), Exchange: str("mail.example.org")}}}),
+			recordSet("example.org.", azuredns.RecordSetProperties{TxtRecords: &[]azuredns.TxtRecord{{Value: &[]string{"v=spf1 -all"}}}}),
+			recordSet("www.example.org.", azuredns.RecordSetProperties{CnameRecord: &azuredns.CnameRecord{Cname: str("example.org")}}),
+			recordSet("_sip._tcp.example.org.", azuredns.RecordSetProperties{SrvRecords: &[]azuredns.SrvRecord{{Priority: i32(10), Weight: i32(20), Port: i32(5060), Target: str("sip.example.org")}}}),
+			recordSet("bad.example.org.", azuredns.RecordSetProperties{ARecords: &[]azuredns.ARecord{{Ipv4Address: str("not-an-ip")}}}),
+		},
+		"rg2": {
+			soa,
+			recordSet("other.example.org.", azuredns.RecordSetProperties{ARecords: &[]azuredns.ARecord{{Ipv4Address: str("3.5.7.9")}}}),
+		},
+	}
+	return sets[resourceGroup], nil
+}
+
+func TestAzure(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := New(ctx, fakeAzure{}, map[string][]string{"example.org.": {"nosuch"}}, &upstream.Upstream{}); err == nil {
+		t.Fatal("Expected error for non-existing zone")
+	}
+
+	r, err := New(ctx, fakeAzure{}, map[string][]string{"bad.": {"bad"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Azure: %v", err)
+	}
+	if err = r.Run(ctx); err == nil {
+		t.Fatalf("Expected errors for zone bad.")
+	}
+
+	r, err = New(ctx, fakeAzure{}, map[string][]string{"example.org.": {"rg1", "rg2"}, "example.gov.": {"rg2"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Azure: %v", err)
+	}
+	r.Fall = fall.Zero
+	r.Fall.SetZonesFromArgs([]string{"example.gov."})
+	r.Next = test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		state := crequest.Request{W: w, Req: r}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A(state.QName() + " 300 IN A 2.4.6.8")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Failed to initialize Azure: %v", err)
+	}
+
+	soa := test.SOA("example.org. 300 IN SOA ns1-03.azure-dns.com. azuredns-hostmaster.microsoft.com. 1 3600 300 2419200 300")
+	tests := []test.Case{
+		{
+			Qname: "example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("example.org. 300 IN A 1.2.3.4")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("example.org. 300 IN AAAA 2001:db8::1")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{test.MX("example.org. 300 IN MX 10 mail.example.org.")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeTXT,
+			Answer: []dns.RR{test.TXT(`example.org. 300 IN TXT "v=spf1 -all"`)},
+		},
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("example.org. 300 IN A 1.2.3.4"),
+				test.CNAME("www.example.org. 300 IN CNAME example.org."),
+			},
+		},
+		{
+			Qname: "_sip._tcp.example.org.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("_sip._tcp.example.org. 300 IN SRV 10 20 5060 sip.example.org.")},
+		},
+		// Stored in the second resource group.
+		{
+			Qname: "other.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("other.example.org. 300 IN A 3.5.7.9")},
+		},
+		// Invalid record set is skipped.
+		{
+			Qname: "bad.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{soa},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeSRV,
+			Ns: []dns.RR{soa},
+		},
+		// Fallthrough.
+		{
+			Qname: "www.example.gov.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.gov. 300 IN A 2.4.6.8")},
+		},
+	}
+
+	for i, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(ctx, rec, tc.Msg()); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
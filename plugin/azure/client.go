@@ -0,0 +1,37 @@
+package azure
+
+import (
+	"context"
+
+	azuredns "github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+)
+
+// Client is the part of the Azure DNS API the plugin uses.
+type Client interface {
+	// GetZone returns an error if zone doesn't exist in resourceGroup.
+	GetZone(ctx context.Context, resourceGroup, zone string) error
+	// ListRecordSets returns all record sets in zone in resourceGroup.
+	ListRecordSets(ctx context.Context, resourceGroup, zone string) ([]azuredns.RecordSet, error)
+}
+
+// client implements Client with the Azure SDK.
+type client struct {
+	zones      azuredns.ZonesClient
+	recordSets azuredns.RecordSetsClient
+}
+
+// GetZone implements the Client interface.
+func (c *client) GetZone(ctx context.Context, resourceGroup, zone string) error {
+	_, err := c.zones.Get(ctx, resourceGroup, zone)
+	return err
+}
+
+// ListRecordSets implements the Client interface.
+func (c *client) ListRecordSets(ctx context.Context, resourceGroup, zone string) ([]azuredns.RecordSet, error) {
+	var sets []azuredns.RecordSet
+	page, err := c.recordSets.ListAllByDNSZone(ctx, resourceGroup, zone, nil, "")
+	for ; err == nil && page.NotDone(); err = page.NextWithContext(ctx) {
+		sets = append(sets, page.Values()...)
+	}
+	return sets, err
+}
@@ -0,0 +1,188 @@
+package azure
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	azuredns "github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("azure")
+
+func init() {
+	caddy.RegisterPlugin("azure", caddy.Plugin{
+		ServerType: "dns",
+		Action: func(c *caddy.Controller) error {
+			return setup(c, newClient)
+		},
+	})
+}
+
+// credentials holds the settings used to access the Azure API.
+type credentials struct {
+	tenant       string
+	client       string
+	secret       string
+	subscription string
+	environment  string
+}
+
+// newClient returns a Client for the Azure DNS API. When no tenant, client and secret are given
+// the credentials are read from the environment, in the same way the Azure CLI does.
+func newClient(cred credentials) (Client, error) {
+	env := azure.PublicCloud
+	if cred.environment != "" {
+		var err error
+		if env, err = azure.EnvironmentFromName(cred.environment); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		authorizer autorest.Authorizer
+		err        error
+	)
+	if cred.tenant != "" || cred.client != "" || cred.secret != "" {
+		config := auth.NewClientCredentialsConfig(cred.client, cred.secret, cred.tenant)
+		config.AADEndpoint = env.ActiveDirectoryEndpoint
+		config.Resource = env.ResourceManagerEndpoint
+		authorizer, err = config.Authorizer()
+	} else {
+		authorizer, err = auth.NewAuthorizerFromEnvironmentWithResource(env.ResourceManagerEndpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		zones:      azuredns.NewZonesClientWithBaseURI(env.ResourceManagerEndpoint, cred.subscription),
+		recordSets: azuredns.NewRecordSetsClientWithBaseURI(env.ResourceManagerEndpoint, cred.subscription),
+	}
+	c.zones.Authorizer = authorizer
+	c.recordSets.Authorizer = authorizer
+	return c, nil
+}
+
+func setup(c *caddy.Controller, f func(credentials) (Client, error)) error {
+	keyPairs := map[string]struct{}{}
+	keys := map[string][]string{}
+
+	var cred credentials
+	var fall fall.F
+	refresh := defaultRefresh
+
+	up := upstream.New()
+	for c.Next() {
+		args := c.RemainingArgs()
+
+		for i := 0; i < len(args); i++ {
+			parts := strings.SplitN(args[i], ":", 2)
+			if len(parts) != 2 {
+				return c.Errf("invalid resource group / zone '%s'", args[i])
+			}
+			resourceGroup, dns := parts[0], parts[1]
+			if resourceGroup == "" || dns == "" {
+				return c.Errf("invalid resource group / zone '%s'", args[i])
+			}
+			dns = plugin.Host(dns).Normalize()
+			if _, ok := keyPairs[resourceGroup+":"+dns]; ok {
+				return c.Errf("conflict zone '%s'", args[i])
+			}
+
+			keyPairs[resourceGroup+":"+dns] = struct{}{}
+			keys[dns] = append(keys[dns], resourceGroup)
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "tenant":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				cred.tenant = c.Val()
+			case "client":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				cred.client = c.Val()
+			case "secret":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				cred.secret = c.Val()
+			case "subscription":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				cred.subscription = c.Val()
+			case "environment":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				cred.environment = c.Val()
+			case "upstream":
+				c.RemainingArgs() // eats args
+			case "refresh":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return c.Errf("unable to parse refresh duration '%s': %v", c.Val(), err)
+				}
+				if d <= 0 {
+					return c.Errf("refresh interval must be greater than 0: %s", c.Val())
+				}
+				refresh = d
+			case "fallthrough":
+				fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if cred.subscription == "" {
+		return c.Errf("subscription is required")
+	}
+	if (cred.tenant != "" || cred.client != "" || cred.secret != "") && (cred.tenant == "" || cred.client == "" || cred.secret == "") {
+		return c.Errf("tenant, client and secret must be given together")
+	}
+
+	client, err := f(cred)
+	if err != nil {
+		return c.Errf("failed to create Azure client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h, err := New(ctx, client, keys, up)
+	if err != nil {
+		cancel()
+		return c.Errf("failed to create Azure plugin: %v", err)
+	}
+	h.Fall = fall
+	h.refresh = refresh
+	if err := h.Run(ctx); err != nil {
+		cancel()
+		return c.Errf("failed to initialize Azure plugin: %v", err)
+	}
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		h.Next = next
+		return h
+	})
+
+	return nil
+}
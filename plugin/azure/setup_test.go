@@ -0,0 +1,67 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupAzure(t *testing.T) {
+	f := func(credentials) (Client, error) {
+		return fakeAzure{}, nil
+	}
+
+	tests := []struct {
+		body          string
+		expectedError bool
+	}{
+		{`azure`, true},
+		{`azure rg1:example.org {
+    subscription 12345678
+}`, false},
+		{`azure rg1:example.org rg2:example.org {
+    tenant TENANT
+    client CLIENT
+    secret SECRET
+    subscription 12345678
+    environment AZUREPUBLICCLOUD
+    upstream
+    refresh 5m
+    fallthrough
+}`, false},
+		{`azure : {
+    subscription 12345678
+}`, true},
+		{`azure rg1 {
+    subscription 12345678
+}`, true},
+		{`azure rg1:example.org rg1:example.org {
+    subscription 12345678
+}`, true},
+		{`azure rg1:example.org {
+    subscription 12345678
+    tenant TENANT
+}`, true},
+		{`azure rg1:example.org {
+    subscription
+}`, true},
+		{`azure rg1:example.org {
+    subscription 12345678
+    refresh 0s
+}`, true},
+		{`azure rg1:example.org {
+    subscription 12345678
+    wat
+}`, true},
+		{`azure nosuch:example.org {
+    subscription 12345678
+}`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.body)
+		if err := setup(c, f); (err == nil) == test.expectedError {
+			t.Errorf("Test %d: unexpected errors: %v", i, err)
+		}
+	}
+}
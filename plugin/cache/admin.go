@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+
+	"github.com/miekg/dns"
+)
+
+var uniqAdmin = uniq.New()
+
+// admin is an HTTP API to inspect and manipulate caches at runtime. All caches configured
+// with the same address share one admin; each cache is identified by its server block.
+type admin struct {
+	*http.ServeMux
+
+	sync.RWMutex
+	caches map[string]*Cache
+}
+
+func newAdmin() *admin {
+	a := &admin{ServeMux: http.NewServeMux(), caches: make(map[string]*Cache)}
+	a.HandleFunc("/cache", a.servers)
+	a.HandleFunc("/cache/", a.serveHTTP)
+	return a
+}
+
+// add registers c under the name of its server block.
+func (a *admin) add(server string, c *Cache) {
+	a.Lock()
+	a.caches[server] = c
+	a.Unlock()
+}
+
+// servers lists the server blocks that have a cache.
+func (a *admin) servers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	a.RLock()
+	names := make([]string, 0, len(a.caches))
+	for s := range a.caches {
+		names = append(names, s)
+	}
+	a.RUnlock()
+
+	sort.Strings(names)
+	for _, s := range names {
+		fmt.Fprintln(w, s)
+	}
+}
+
+// serveHTTP handles /cache/<server>/<action>.
+func (a *admin) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	el := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/cache/"), "/", 2)
+	if len(el) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	a.RLock()
+	c, ok := a.caches[el[0]]
+	a.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("no cache for server block %q", el[0]), http.StatusNotFound)
+		return
+	}
+
+	method := http.MethodGet
+	switch el[1] {
+	case "purge", "flush":
+		method = http.MethodPost
+	case "keys", "entry":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != method {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	switch el[1] {
+	case "keys":
+		c.keys(w)
+
+	case "entry":
+		name := q.Get("name")
+		qtype, ok := dns.StringToType[strings.ToUpper(q.Get("type"))]
+		if name == "" || !ok {
+			http.Error(w, "need name and type", http.StatusBadRequest)
+			return
+		}
+		if !c.entry(w, dns.Fqdn(strings.ToLower(name)), qtype) {
+			http.NotFound(w, r)
+		}
+
+	case "purge":
+		name, suffix := q.Get("name"), q.Get("suffix")
+		if (name == "") == (suffix == "") {
+			http.Error(w, "need either name or suffix", http.StatusBadRequest)
+			return
+		}
+		var n int
+		if name != "" {
+			name = dns.Fqdn(strings.ToLower(name))
+			n = c.purge(func(i *item) bool { return i.Name == name })
+		} else {
+			suffix = dns.Fqdn(strings.ToLower(suffix))
+			n = c.purge(func(i *item) bool { return dns.IsSubDomain(suffix, i.Name) })
+		}
+		fmt.Fprintf(w, "%d\n", n)
+
+	case "flush":
+		n := c.purge(func(*item) bool { return true })
+		fmt.Fprintf(w, "%d\n", n)
+	}
+}
+
+// typed pairs a cache with its type, either Success or Denial.
+type typed struct {
+	typ string
+	c   *cache.Cache
+}
+
+func (c *Cache) typed() []typed { return []typed{{Success, c.pcache}, {Denial, c.ncache}} }
+
+// keys writes a line for each item in the caches to w: the cache type, the remaining TTL, the
+// name and the type.
+func (c *Cache) keys(w io.Writer) {
+	now := c.now()
+	for _, ca := range c.typed() {
+		ca.c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i := items[key].(*item)
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", ca.typ, i.ttl(now), i.Name, dns.TypeToString[i.QType])
+			return true
+		})
+	}
+}
+
+// entry writes the cached messages for name and qtype to w, with and without the DO bit and for
+// each client subnet. It returns false when nothing is cached.
+func (c *Cache) entry(w io.Writer, name string, qtype uint16) bool {
+	type cached struct {
+		typ string
+		do  bool
+		i   *item
+	}
+	// Replies scoped to a client subnet are stored under two keys, only list them once.
+	seen := map[*item]bool{}
+	found := []cached{}
+	for _, ca := range c.typed() {
+		ca.c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i := items[key].(*item)
+			if i.Name != name || i.QType != qtype || seen[i] {
+				return true
+			}
+			seen[i] = true
+			k := hash(name, qtype, true)
+			do := key == k || (i.Subnet != nil && key == subnetKey(k, i.Subnet))
+			found = append(found, cached{ca.typ, do, i})
+			return true
+		})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return !found[i].do && found[j].do })
+
+	now := c.now()
+	for _, f := range found {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		if f.i.Subnet != nil {
+			fmt.Fprintf(w, ";; %s cache, DO bit %t, client subnet %s\n", f.typ, f.do, f.i.Subnet)
+		} else {
+			fmt.Fprintf(w, ";; %s cache, DO bit %t\n", f.typ, f.do)
+		}
+		fmt.Fprintln(w, f.i.toMsg(req, now).String())
+	}
+	return len(found) > 0
+}
+
+// purge removes all items for which match returns true from the caches and returns the number
+// of removed items. A reply scoped to a client subnet is stored under two keys, both are removed
+// and it is counted once.
+func (c *Cache) purge(match func(*item) bool) int {
+	removed := map[*item]bool{}
+	for _, ca := range c.typed() {
+		ca.c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			if i := items[key].(*item); match(i) {
+				delete(items, key)
+				removed[i] = true
+			}
+			return true
+		})
+	}
+	return len(removed)
+}
+
+const defAdminAddr = "localhost:8182"
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/adminapi"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestAdmin(t *testing.T) {
+	now := time.Now().UTC()
+	c := New()
+	c.now = func() time.Time { return now }
+
+	for _, name := range []string{"a.example.org.", "b.example.org.", "example.net."} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		m.Answer = []dns.RR{test.A(name + " 3600 IN A 127.0.0.53")}
+		c.pcache.Add(hash(name, dns.TypeA, false), newItem(m, now, 60*time.Second))
+	}
+	m := new(dns.Msg)
+	m.SetQuestion("a.example.org.", dns.TypeAAAA)
+	m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA sns.dns.icann.org. noc.dns.icann.org. 2016082540 7200 3600 1209600 3600")}
+	c.ncache.Add(hash("a.example.org.", dns.TypeAAAA, false), newItem(m, now, 60*time.Second))
+
+	a := newAdmin()
+	a.add("example.org.:53", c)
+	l := adminapi.New("localhost:0", a)
+	if err := l.Start(); err != nil {
+		t.Fatalf("Unable to startup the admin server: %v", err)
+	}
+	defer l.Stop()
+
+	base := fmt.Sprintf("http://%s/cache", l.Addr().String())
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		contains string
+		len      int // expected size of the caches after the request
+	}{
+		{http.MethodGet, "", http.StatusOK, "example.org.:53", 4},
+		{http.MethodGet, "/example.net.:53/keys", http.StatusNotFound, "", 4},
+		{http.MethodGet, "/example.org.:53/keys", http.StatusOK, "denial\t60\ta.example.org.\tAAAA", 4},
+		{http.MethodGet, "/example.org.:53/entry?name=B.example.org&type=a", http.StatusOK, "b.example.org.\t60\tIN\tA\t127.0.0.53", 4},
+		{http.MethodGet, "/example.org.:53/entry?name=c.example.org&type=A", http.StatusNotFound, "", 4},
+		{http.MethodGet, "/example.org.:53/entry?name=c.example.org", http.StatusBadRequest, "", 4},
+		{http.MethodGet, "/example.org.:53/flush", http.StatusMethodNotAllowed, "", 4},
+		{http.MethodPost, "/example.org.:53/purge", http.StatusBadRequest, "", 4},
+		{http.MethodPost, "/example.org.:53/purge?name=a.example.org.", http.StatusOK, "2", 2},
+		{http.MethodPost, "/example.org.:53/purge?suffix=org", http.StatusOK, "1", 1},
+		{http.MethodPost, "/example.org.:53/flush", http.StatusOK, "1", 0},
+	}
+
+	for i, tc := range tests {
+		req, _ := http.NewRequest(tc.method, base+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: unable to query %s: %v", i, tc.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code {
+			t.Errorf("Test %d: expected status code %d, got %d", i, tc.code, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), tc.contains) {
+			t.Errorf("Test %d: expected body to contain %q, got %q", i, tc.contains, body)
+		}
+		if l := c.pcache.Len() + c.ncache.Len(); l != tc.len {
+			t.Errorf("Test %d: expected %d items in the cache, got %d", i, tc.len, l)
+		}
+	}
+}
+
+func TestAdminClientSubnet(t *testing.T) {
+	now := time.Now().UTC()
+	c := New()
+	c.now = func() time.Time { return now }
+
+	k := hash("ecs.example.org.", dns.TypeA, false)
+	for _, subnet := range []string{"192.0.2.0/24", "198.51.100.0/24"} {
+		m := new(dns.Msg)
+		m.SetQuestion("ecs.example.org.", dns.TypeA)
+		m.Answer = []dns.RR{test.A("ecs.example.org. 3600 IN A 127.0.0.53")}
+		i := newItem(m, now, 60*time.Second)
+		_, i.Subnet, _ = net.ParseCIDR(subnet)
+		add(c.pcache, k, i)
+	}
+	// The first reply is only stored under its subnet key, the second under both.
+	if l := c.pcache.Len(); l != 3 {
+		t.Fatalf("Expected %d items in the cache, got %d", 3, l)
+	}
+
+	a := newAdmin()
+	a.add("example.org.:53", c)
+	l := adminapi.New("localhost:0", a)
+	if err := l.Start(); err != nil {
+		t.Fatalf("Unable to startup the admin server: %v", err)
+	}
+	defer l.Stop()
+
+	base := fmt.Sprintf("http://%s/cache/example.org.:53", l.Addr().String())
+
+	resp, err := http.Get(base + "/entry?name=ecs.example.org&type=A")
+	if err != nil {
+		t.Fatalf("Unable to query entry: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, subnet := range []string{"client subnet 192.0.2.0/24", "client subnet 198.51.100.0/24"} {
+		if strings.Count(string(body), subnet) != 1 {
+			t.Errorf("Expected body to contain %q once, got %q", subnet, body)
+		}
+	}
+
+	resp, err = http.Post(base+"/purge?name=ecs.example.org", "", nil)
+	if err != nil {
+		t.Fatalf("Unable to purge: %v", err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "2\n" {
+		t.Errorf("Expected %d entries to be purged, got %q", 2, body)
+	}
+	if l := c.pcache.Len(); l != 0 {
+		t.Errorf("Expected an empty cache, got %d items", l)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
 
@@ -25,17 +26,26 @@ type Cache struct {
 	ncap    int
 	nttl    time.Duration
 	minnttl time.Duration
+	npolicy cache.Policy
 
 	pcache  *cache.Cache
 	pcap    int
 	pttl    time.Duration
 	minpttl time.Duration
+	ppolicy cache.Policy
 
 	// Prefetch.
 	prefetch   int
 	duration   time.Duration
 	percentage int
 
+	// Snapshot.
+	snapshot         string // file to save the cache to, empty means disabled
+	snapshotInterval time.Duration
+
+	// Admin API, empty means disabled.
+	admin string
+
 	// Testing.
 	now func() time.Time
 }
@@ -57,6 +67,8 @@ func New() *Cache {
 		duration:   1 * time.Minute,
 		percentage: 10,
 		now:        time.Now,
+
+		snapshotInterval: 1 * time.Minute,
 	}
 }
 
@@ -94,6 +106,28 @@ func hash(qname string, qtype uint16, do bool) uint64 {
 	return h.Sum64()
 }
 
+// subnetKey returns the key under which a reply for key k, that is scoped to the client subnet n, is stored.
+func subnetKey(k uint64, n *net.IPNet) uint64 {
+	h := fnv.New64()
+	h.Write([]byte{byte(k >> 56), byte(k >> 48), byte(k >> 40), byte(k >> 32), byte(k >> 24), byte(k >> 16), byte(k >> 8), byte(k)})
+	h.Write(n.IP)
+	h.Write(n.Mask)
+	return h.Sum64()
+}
+
+// clientSubnet returns the client subnet of the query in state, this is taken from the EDNS0 client
+// subnet option or, when there is none, the client's address.
+func clientSubnet(state request.Request) (net.IP, int) {
+	if e := edns.ClientSubnet(state.Req); e != nil {
+		return e.Address, int(e.SourceNetmask)
+	}
+	ip := net.ParseIP(state.IP())
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, net.IPv4len * 8
+	}
+	return ip, net.IPv6len * 8
+}
+
 func computeTTL(msgTTL, minTTL, maxTTL time.Duration) time.Duration {
 	ttl := msgTTL
 	if ttl < minTTL {
@@ -207,11 +241,11 @@ func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration
 	switch mt {
 	case response.NoError, response.Delegation:
 		i := newItem(m, w.now(), duration)
-		w.pcache.Add(key, i)
+		add(w.pcache, key, i)
 
 	case response.NameError, response.NoData, response.ServerError:
 		i := newItem(m, w.now(), duration)
-		w.ncache.Add(key, i)
+		add(w.ncache, key, i)
 
 	case response.OtherError:
 		// don't cache these
@@ -220,6 +254,15 @@ func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration
 	}
 }
 
+// add adds i to ca under key. A reply scoped to a client subnet is also added under its subnet key, so
+// replies for other subnets don't push it out; the one under key tells get which subnet key to use.
+func add(ca *cache.Cache, key uint64, i *item) {
+	ca.Add(key, i)
+	if i.Subnet != nil {
+		ca.Add(subnetKey(key, i.Subnet), i)
+	}
+}
+
 // Write implements the dns.ResponseWriter interface.
 func (w *ResponseWriter) Write(buf []byte) (int, error) {
 	log.Warning("Caching called with Write: not caching reply")
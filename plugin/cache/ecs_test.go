@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestClientSubnet(t *testing.T) {
+	c := New()
+	n := 0
+	c.Next = scopeHandler(24, &n)
+
+	tests := []struct {
+		subnet   string // empty for no client subnet option
+		upstream int    // number of queries the upstream has seen after this query
+	}{
+		{"192.0.2.0/24", 1},
+		{"192.0.2.0/24", 1},
+		{"192.0.2.128/25", 1}, // within the scope of the first reply
+		{"198.51.100.0/24", 2},
+		{"192.0.2.0/24", 2}, // still cached under its subnet key
+		{"192.0.0.0/16", 3}, // source prefix is shorter than the scope
+		{"", 4},             // 10.240.0.1
+		{"", 4},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		if tc.subnet != "" {
+			_, ipnet, _ := net.ParseCIDR(tc.subnet)
+			ones, _ := ipnet.Mask.Size()
+			edns.SetClientSubnet(req, edns.NewClientSubnet(ipnet.IP, uint8(ones), uint8(ones)))
+		}
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+		if n != tc.upstream {
+			t.Errorf("Test %d: expected %d upstream queries, got %d", i, tc.upstream, n)
+		}
+		if tc.subnet == "" {
+			continue
+		}
+		e := edns.ClientSubnet(rec.Msg)
+		if e == nil {
+			t.Errorf("Test %d: expected client subnet in reply", i)
+			continue
+		}
+		if x := edns.Subnet(e, e.SourceNetmask).String(); x != tc.subnet {
+			t.Errorf("Test %d: expected client subnet %s in reply, got %s", i, tc.subnet, x)
+		}
+	}
+}
+
+func TestClientSubnetScopeZero(t *testing.T) {
+	c := New()
+	n := 0
+	c.Next = scopeHandler(0, &n)
+
+	for _, subnet := range []string{"192.0.2.0", "198.51.100.0"} {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		edns.SetClientSubnet(req, edns.NewClientSubnet(net.ParseIP(subnet), 24, 56))
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+	}
+	if n != 1 {
+		t.Errorf("Expected reply with scope 0 to be used for all clients, got %d upstream queries", n)
+	}
+}
+
+// scopeHandler returns a reply that echoes the client subnet of the query, or one for the client's
+// address, with scope prefix length scope. It counts the queries it sees in n.
+func scopeHandler(scope uint8, n *int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*n++
+		state := request.Request{W: w, Req: r}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A 127.0.0.1")}
+
+		e := edns.ClientSubnet(r)
+		if e == nil {
+			e = edns.NewClientSubnet(net.ParseIP(state.IP()), 24, 56)
+		}
+		e = &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: e.Family, SourceNetmask: e.SourceNetmask, SourceScope: scope, Address: e.Address}
+		edns.SetClientSubnet(m, e)
+
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
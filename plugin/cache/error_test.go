@@ -6,6 +6,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -36,3 +37,44 @@ func formErrHandler() plugin.Handler {
 		return dns.RcodeSuccess, nil
 	})
 }
+
+func TestExtendedError(t *testing.T) {
+	c := New()
+	c.Next = servFailHandler()
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	req.SetEdns0(4096, false)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if c.ncache.Len() != 1 {
+		t.Fatalf("Expected SERVFAIL to be cached")
+	}
+
+	// From the cache.
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	ede := edns.ExtendedErrors(rec.Msg)
+	if len(ede) != 2 {
+		t.Fatalf("Expected 2 extended errors, got %v", ede)
+	}
+	if ede[0].InfoCode != edns.ExtendedErrorCodeNetworkError || ede[0].ExtraText != "i/o timeout" {
+		t.Errorf("Expected cached extended error, got %s", ede[0])
+	}
+	if ede[1].InfoCode != edns.ExtendedErrorCodeCachedError {
+		t.Errorf("Expected Cached Error extended error, got %s", ede[1])
+	}
+}
+
+// servFailHandler is a fake plugin implementation which returns a SERVFAIL with an extended error.
+func servFailHandler() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		m.SetEdns0(4096, false)
+		edns.AddExtendedError(m, edns.ExtendedError{InfoCode: edns.ExtendedErrorCodeNetworkError, ExtraText: "i/o timeout"})
+		w.WriteMsg(m)
+		return dns.RcodeServerFailure, nil
+	})
+}
@@ -3,10 +3,12 @@ package cache
 import (
 	"context"
 	"math"
+	"net"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -65,14 +67,14 @@ func (c *Cache) Name() string { return "cache" }
 func (c *Cache) get(now time.Time, state request.Request, server string) (*item, bool) {
 	k := hash(state.Name(), state.QType(), state.Do())
 
-	if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
+	if i := find(c.ncache, k, state); i != nil && i.ttl(now) > 0 {
 		cacheHits.WithLabelValues(server, Denial).Inc()
-		return i.(*item), true
+		return i, true
 	}
 
-	if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
+	if i := find(c.pcache, k, state); i != nil && i.ttl(now) > 0 {
 		cacheHits.WithLabelValues(server, Success).Inc()
-		return i.(*item), true
+		return i, true
 	}
 	cacheMisses.WithLabelValues(server).Inc()
 	return nil, false
@@ -80,10 +82,33 @@ func (c *Cache) get(now time.Time, state request.Request, server string) (*item,
 
 func (c *Cache) exists(state request.Request) *item {
 	k := hash(state.Name(), state.QType(), state.Do())
-	if i, ok := c.ncache.Get(k); ok {
+	if i := find(c.ncache, k, state); i != nil {
+		return i
+	}
+	return find(c.pcache, k, state)
+}
+
+// find returns the item stored under k in ca that can be used to answer the query in state. If the
+// item is scoped to another client subnet, the item for the client's subnet is looked up instead.
+func find(ca *cache.Cache, k uint64, state request.Request) *item {
+	i, ok := ca.Get(k)
+	if !ok {
+		return nil
+	}
+	if i.(*item).Subnet == nil {
 		return i.(*item)
 	}
-	if i, ok := c.pcache.Get(k); ok {
+	ip, prefix := clientSubnet(state)
+	if i.(*item).usable(ip, prefix) {
+		return i.(*item)
+	}
+
+	mask := i.(*item).Subnet.Mask
+	n := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	if n.IP == nil {
+		return nil
+	}
+	if i, ok = ca.Get(subnetKey(k, n)); ok && i.(*item).usable(ip, prefix) {
 		return i.(*item)
 	}
 	return nil
@@ -1,13 +1,20 @@
 package cache
 
 import (
+	"net"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/plugin/cache/freq"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
 	"github.com/miekg/dns"
 )
 
 type item struct {
+	Name  string // lowercased qname, used by the admin API and snapshots
+	QType uint16
+
 	Rcode              int
 	Authoritative      bool
 	AuthenticatedData  bool
@@ -15,6 +22,8 @@ type item struct {
 	Answer             []dns.RR
 	Ns                 []dns.RR
 	Extra              []dns.RR
+	ExtendedErrors     []edns.ExtendedError
+	Subnet             *net.IPNet // client subnet the reply is valid for, nil for all clients
 
 	origTTL uint32
 	stored  time.Time
@@ -24,6 +33,10 @@ type item struct {
 
 func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	i := new(item)
+	if len(m.Question) > 0 {
+		i.Name = strings.ToLower(m.Question[0].Name)
+		i.QType = m.Question[0].Qtype
+	}
 	i.Rcode = m.Rcode
 	i.Authoritative = m.Authoritative
 	i.AuthenticatedData = m.AuthenticatedData
@@ -41,6 +54,19 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 		j++
 	}
 	i.Extra = i.Extra[:j]
+	// But do keep the extended errors from the OPT record.
+	i.ExtendedErrors = edns.ExtendedErrors(m)
+	// And the client subnet the reply is scoped to, a scope longer than the source prefix length
+	// is limited to the source prefix length, see RFC 7871, section 7.3.1.
+	if e := edns.ClientSubnet(m); e != nil {
+		scope := e.SourceScope
+		if scope > e.SourceNetmask {
+			scope = e.SourceNetmask
+		}
+		if scope > 0 {
+			i.Subnet = edns.Subnet(e, scope)
+		}
+	}
 
 	i.origTTL = uint32(d.Seconds())
 	i.stored = now.UTC()
@@ -79,9 +105,66 @@ func (i *item) toMsg(m *dns.Msg, now time.Time) *dns.Msg {
 		m1.Extra[j] = dns.Copy(r)
 		m1.Extra[j].Header().Ttl = ttl
 	}
+
+	if m.IsEdns0() == nil {
+		return m1
+	}
+	if e := edns.ClientSubnet(m); e != nil {
+		scope := uint8(0)
+		if i.Subnet != nil {
+			ones, _ := i.Subnet.Mask.Size()
+			scope = uint8(ones)
+		}
+		edns.SetClientSubnet(m1, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: e.Family, SourceNetmask: e.SourceNetmask, SourceScope: scope, Address: e.Address})
+	}
+	for _, e := range i.ExtendedErrors {
+		edns.AddExtendedError(m1, e)
+	}
+	if i.Rcode == dns.RcodeServerFailure {
+		edns.AddExtendedError(m1, edns.ExtendedError{InfoCode: edns.ExtendedErrorCodeCachedError})
+	}
 	return m1
 }
 
+// pack returns i as a message in wire format. The TTLs are left as they were when i was created.
+func (i *item) pack() ([]byte, error) {
+	m := new(dns.Msg)
+	if i.Name != "" {
+		m.Question = []dns.Question{{Name: i.Name, Qtype: i.QType, Qclass: dns.ClassINET}}
+	}
+	m.Response = true
+	m.Rcode = i.Rcode
+	m.Authoritative = i.Authoritative
+	m.AuthenticatedData = i.AuthenticatedData
+	m.RecursionAvailable = i.RecursionAvailable
+	m.Answer = i.Answer
+	m.Ns = i.Ns
+	m.Extra = i.Extra
+	if len(i.ExtendedErrors) > 0 || i.Subnet != nil {
+		m.Extra = make([]dns.RR, len(i.Extra), len(i.Extra)+1)
+		copy(m.Extra, i.Extra)
+		for _, e := range i.ExtendedErrors {
+			edns.AddExtendedError(m, e)
+		}
+		if i.Subnet != nil {
+			ones, _ := i.Subnet.Mask.Size()
+			e := edns.NewClientSubnet(i.Subnet.IP, uint8(ones), uint8(ones))
+			e.SourceScope = uint8(ones)
+			edns.SetClientSubnet(m, e)
+		}
+	}
+	return m.Pack()
+}
+
+// usable returns true if i can be used to answer a query from a client in the subnet ip/prefix.
+func (i *item) usable(ip net.IP, prefix int) bool {
+	if i.Subnet == nil {
+		return true
+	}
+	ones, _ := i.Subnet.Mask.Size()
+	return prefix >= ones && i.Subnet.Contains(ip)
+}
+
 func (i *item) ttl(now time.Time) int {
 	ttl := int(i.origTTL) - int(now.UTC().Sub(i.stored).Seconds())
 	return ttl
@@ -2,12 +2,15 @@ package cache
 
 import (
 	"fmt"
+	"net"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/adminapi"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 
@@ -40,6 +43,41 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	if ca.snapshot != "" {
+		// A failed snapshot should not prevent a reload or shutdown, so we only log.
+		save := func() error {
+			if err := ca.saveSnapshot(); err != nil {
+				log.Warningf("Failed to write snapshot %q: %s", ca.snapshot, err)
+			}
+			return nil
+		}
+
+		var stop chan struct{}
+		c.OnStartup(func() error {
+			if err := ca.loadSnapshot(); err != nil {
+				log.Warningf("Failed to load snapshot %q: %s", ca.snapshot, err)
+			}
+			stop = ca.periodicSnapshot()
+			return nil
+		})
+		// On reload the new instance starts before the old one is shut down, so save now.
+		c.OnRestart(save)
+		c.OnShutdown(func() error {
+			if stop != nil {
+				close(stop)
+			}
+			return nil
+		})
+		c.OnFinalShutdown(save)
+	}
+
+	if ca.admin != "" {
+		a := adminapi.Register(c, uniqAdmin, ca.admin, newAdmin()).(*admin)
+
+		config := dnsserver.GetConfig(c)
+		a.add(net.JoinHostPort(config.Zone, config.Port), ca)
+	}
+
 	return nil
 }
 
@@ -84,6 +122,11 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 				if len(args) == 0 {
 					return nil, c.ArgErr()
 				}
+				args, policy, err := parseEviction(args)
+				if err != nil {
+					return nil, err
+				}
+				ca.ppolicy = policy
 				pcap, err := strconv.Atoi(args[0])
 				if err != nil {
 					return nil, err
@@ -116,6 +159,11 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 				if len(args) == 0 {
 					return nil, c.ArgErr()
 				}
+				args, policy, err := parseEviction(args)
+				if err != nil {
+					return nil, err
+				}
+				ca.npolicy = policy
 				ncap, err := strconv.Atoi(args[0])
 				if err != nil {
 					return nil, err
@@ -181,6 +229,41 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 					ca.percentage = num
 				}
 
+			case "snapshot":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				ca.snapshot = args[0]
+				config := dnsserver.GetConfig(c)
+				if !filepath.IsAbs(ca.snapshot) && config.Root != "" {
+					ca.snapshot = filepath.Join(config.Root, ca.snapshot)
+				}
+				if len(args) > 1 {
+					dur, err := time.ParseDuration(args[1])
+					if err != nil {
+						return nil, err
+					}
+					if dur <= 0 {
+						return nil, fmt.Errorf("snapshot interval should be positive: %s", dur)
+					}
+					ca.snapshotInterval = dur
+				}
+
+			case "admin":
+				args := c.RemainingArgs()
+				switch len(args) {
+				case 0:
+					ca.admin = defAdminAddr
+				case 1:
+					if _, _, err := net.SplitHostPort(args[0]); err != nil {
+						return nil, err
+					}
+					ca.admin = args[0]
+				default:
+					return nil, c.ArgErr()
+				}
+
 			default:
 				return nil, c.ArgErr()
 			}
@@ -191,9 +274,28 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 		}
 		ca.Zones = origins
 
-		ca.pcache = cache.New(ca.pcap)
-		ca.ncache = cache.New(ca.ncap)
+		ca.pcache = cache.NewWithPolicy(ca.pcap, ca.ppolicy)
+		ca.ncache = cache.NewWithPolicy(ca.ncap, ca.npolicy)
 	}
 
 	return ca, nil
 }
+
+// parseEviction strips a trailing "eviction POLICY" from args and returns the remaining arguments
+// and the policy. If there is no such suffix the policy is cache.Random.
+func parseEviction(args []string) ([]string, cache.Policy, error) {
+	for i, a := range args {
+		if a != "eviction" {
+			continue
+		}
+		if i == 0 || i != len(args)-2 {
+			return nil, cache.Random, fmt.Errorf("eviction needs a policy and must follow the capacity")
+		}
+		policy, err := cache.ParsePolicy(args[i+1])
+		if err != nil {
+			return nil, cache.Random, err
+		}
+		return args[:i], policy, nil
+	}
+	return args, cache.Random, nil
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
 	"github.com/mholt/caddy"
 )
 
@@ -113,3 +115,152 @@ func TestSetup(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupSnapshot(t *testing.T) {
+	tests := []struct {
+		input            string
+		shouldErr        bool
+		expectedSnapshot string
+		expectedInterval time.Duration
+	}{
+		{`cache`, false, "", 1 * time.Minute},
+		{`cache {
+				snapshot /var/lib/coredns/cache
+			}`, false, "/var/lib/coredns/cache", 1 * time.Minute},
+		{`cache {
+				snapshot /var/lib/coredns/cache 30s
+			}`, false, "/var/lib/coredns/cache", 30 * time.Second},
+		// fails
+		{`cache {
+				snapshot
+			}`, true, "", 0},
+		{`cache {
+				snapshot /var/lib/coredns/cache 0s
+			}`, true, "", 0},
+		{`cache {
+				snapshot /var/lib/coredns/cache blurp
+			}`, true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+
+		if ca.snapshot != test.expectedSnapshot {
+			t.Errorf("Test %v: Expected snapshot %q but found: %q", i, test.expectedSnapshot, ca.snapshot)
+		}
+		if ca.snapshotInterval != test.expectedInterval {
+			t.Errorf("Test %v: Expected snapshot interval %v but found: %v", i, test.expectedInterval, ca.snapshotInterval)
+		}
+	}
+}
+
+func TestSetupAdmin(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedAdmin string
+	}{
+		{`cache`, false, ""},
+		{`cache {
+				admin
+			}`, false, defAdminAddr},
+		{`cache {
+				admin :8053
+			}`, false, ":8053"},
+		// fails
+		{`cache {
+				admin 8053
+			}`, true, ""},
+		{`cache {
+				admin :8053 :8054
+			}`, true, ""},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+
+		if ca.admin != test.expectedAdmin {
+			t.Errorf("Test %v: Expected admin %q but found: %q", i, test.expectedAdmin, ca.admin)
+		}
+	}
+}
+
+func TestSetupEviction(t *testing.T) {
+	tests := []struct {
+		input           string
+		shouldErr       bool
+		expectedPpolicy cache.Policy
+		expectedNpolicy cache.Policy
+		expectedPcap    int
+		expectedPttl    time.Duration
+	}{
+		{`cache`, false, cache.Random, cache.Random, defaultCap, maxTTL},
+		{`cache {
+				success 10 eviction lru
+			}`, false, cache.LRU, cache.Random, 10, maxTTL},
+		{`cache {
+				success 10 1800 eviction tinylfu
+				denial 10 eviction lru
+			}`, false, cache.TinyLFU, cache.LRU, 10, 1800 * time.Second},
+		// fails
+		{`cache {
+				success eviction lru
+			}`, true, cache.Random, cache.Random, defaultCap, maxTTL},
+		{`cache {
+				success 10 eviction
+			}`, true, cache.Random, cache.Random, defaultCap, maxTTL},
+		{`cache {
+				success 10 eviction lru 1800
+			}`, true, cache.Random, cache.Random, defaultCap, maxTTL},
+		{`cache {
+				denial 10 eviction mru
+			}`, true, cache.Random, cache.Random, defaultCap, maxTTL},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+
+		if ca.ppolicy != test.expectedPpolicy {
+			t.Errorf("Test %v: Expected ppolicy %v but found: %v", i, test.expectedPpolicy, ca.ppolicy)
+		}
+		if ca.npolicy != test.expectedNpolicy {
+			t.Errorf("Test %v: Expected npolicy %v but found: %v", i, test.expectedNpolicy, ca.npolicy)
+		}
+		if ca.pcap != test.expectedPcap {
+			t.Errorf("Test %v: Expected pcap %v but found: %v", i, test.expectedPcap, ca.pcap)
+		}
+		if ca.pttl != test.expectedPttl {
+			t.Errorf("Test %v: Expected pttl %v but found: %v", i, test.expectedPttl, ca.pttl)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// entry is how a cached item is stored in a snapshot. The message is stored in wire format.
+type entry struct {
+	Key    uint64
+	Denial bool
+	Stored time.Time
+	TTL    uint32
+	Msg    []byte
+}
+
+// snapshotVersion is written at the start of each snapshot, snapshots with a different version are ignored.
+const snapshotVersion = 1
+
+// saveSnapshot writes the contents of the positive and negative caches to c.snapshot. The file
+// is written to a temporary file first and then renamed, so a crash halfway does not leave a
+// corrupted snapshot behind.
+func (c *Cache) saveSnapshot() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(c.snapshot), filepath.Base(c.snapshot)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails if the rename succeeded, which is fine.
+
+	enc := gob.NewEncoder(tmp)
+	if err := enc.Encode(snapshotVersion); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// Copy the items out first, so lookups don't wait for the disk while Walk holds a shard's lock.
+	type snapItem struct {
+		key    uint64
+		denial bool
+		i      *item
+	}
+	now := c.now()
+	items := []snapItem{}
+	walk := func(ca *cache.Cache, denial bool) {
+		ca.Walk(func(m map[uint64]interface{}, key uint64) bool {
+			if i := m[key].(*item); i.ttl(now) > 0 {
+				items = append(items, snapItem{key: key, denial: denial, i: i})
+			}
+			return true
+		})
+	}
+	walk(c.pcache, false)
+	walk(c.ncache, true)
+
+	n := 0
+	for _, si := range items {
+		buf, err := si.i.pack()
+		if err != nil {
+			continue
+		}
+		if err := enc.Encode(entry{Key: si.key, Denial: si.denial, Stored: si.i.stored, TTL: si.i.origTTL, Msg: buf}); err != nil {
+			tmp.Close()
+			return err
+		}
+		n++
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.snapshot); err != nil {
+		return err
+	}
+	log.Debugf("Wrote %d items to snapshot %q", n, c.snapshot)
+	return nil
+}
+
+// loadSnapshot reads c.snapshot and adds all entries that have not expired yet to the caches. A
+// missing snapshot is not an error.
+func (c *Cache) loadSnapshot() error {
+	f, err := os.Open(c.snapshot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	version := 0
+	if err := dec.Decode(&version); err != nil {
+		return err
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d in %q", version, c.snapshot)
+	}
+
+	now := c.now()
+	n := 0
+	for {
+		e := entry{}
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		m := new(dns.Msg)
+		if err := m.Unpack(e.Msg); err != nil {
+			continue
+		}
+		i := newItem(m, e.Stored, time.Duration(e.TTL)*time.Second)
+		if i.ttl(now) <= 0 {
+			continue
+		}
+
+		if e.Denial {
+			c.ncache.Add(e.Key, i)
+		} else {
+			c.pcache.Add(e.Key, i)
+		}
+		n++
+	}
+	log.Debugf("Loaded %d items from snapshot %q", n, c.snapshot)
+	return nil
+}
+
+// periodicSnapshot saves a snapshot every c.snapshotInterval until the returned channel is closed.
+func (c *Cache) periodicSnapshot() chan struct{} {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(c.snapshotInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := c.saveSnapshot(); err != nil {
+					log.Warningf("Failed to write snapshot %q: %s", c.snapshot, err)
+				}
+			}
+		}
+	}()
+	return stop
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC()
+
+	c := New()
+	c.snapshot = filepath.Join(dir, "snapshot")
+	c.now = func() time.Time { return now }
+
+	pos := new(dns.Msg)
+	pos.SetQuestion("example.org.", dns.TypeA)
+	pos.Answer = []dns.RR{test.A("example.org. 3600 IN A 127.0.0.53")}
+	c.pcache.Add(hash("example.org.", dns.TypeA, false), newItem(pos, now, 60*time.Second))
+
+	neg := new(dns.Msg)
+	neg.SetQuestion("nx.example.org.", dns.TypeA)
+	neg.Rcode = dns.RcodeNameError
+	neg.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA sns.dns.icann.org. noc.dns.icann.org. 2016082540 7200 3600 1209600 3600")}
+	c.ncache.Add(hash("nx.example.org.", dns.TypeA, false), newItem(neg, now, 60*time.Second))
+
+	// Expires before we load the snapshot.
+	short := new(dns.Msg)
+	short.SetQuestion("short.example.org.", dns.TypeA)
+	short.Answer = []dns.RR{test.A("short.example.org. 5 IN A 127.0.0.53")}
+	c.pcache.Add(hash("short.example.org.", dns.TypeA, false), newItem(short, now, 5*time.Second))
+
+	if err := c.saveSnapshot(); err != nil {
+		t.Fatalf("Failed to save snapshot: %s", err)
+	}
+
+	later := now.Add(10 * time.Second)
+	c1 := New()
+	c1.snapshot = c.snapshot
+	c1.now = func() time.Time { return later }
+	if err := c1.loadSnapshot(); err != nil {
+		t.Fatalf("Failed to load snapshot: %s", err)
+	}
+
+	if l := c1.pcache.Len(); l != 1 {
+		t.Errorf("Expected %d positive items, got %d", 1, l)
+	}
+	if l := c1.ncache.Len(); l != 1 {
+		t.Errorf("Expected %d negative items, got %d", 1, l)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	i, found := c1.get(later, request.Request{Req: req}, "dns://:53")
+	if !found {
+		t.Fatal("Expected example.org. to be loaded from the snapshot")
+	}
+	resp := i.toMsg(req, later)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("Expected %d answer, got %d", 1, len(resp.Answer))
+	}
+	if ttl := resp.Answer[0].Header().Ttl; ttl != 50 {
+		t.Errorf("Expected remaining TTL of %d, got %d", 50, ttl)
+	}
+
+	req.SetQuestion("nx.example.org.", dns.TypeA)
+	i, found = c1.get(later, request.Request{Req: req}, "dns://:53")
+	if !found {
+		t.Fatal("Expected nx.example.org. to be loaded from the snapshot")
+	}
+	if i.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeNameError, i.Rcode)
+	}
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	c := New()
+	c.snapshot = filepath.Join(os.TempDir(), "coredns-cache-does-not-exist")
+	if err := c.loadSnapshot(); err != nil {
+		t.Errorf("Expected no error for a missing snapshot, got %s", err)
+	}
+}
@@ -0,0 +1,36 @@
+package clouddns
+
+import (
+	"context"
+
+	gcp "google.golang.org/api/dns/v1"
+)
+
+// Client is the part of the Google Cloud DNS API the plugin uses.
+type Client interface {
+	// GetZone returns an error if the managed zone hostedZone doesn't exist in project.
+	GetZone(ctx context.Context, project, hostedZone string) error
+	// ListRecordSets returns all record sets in the managed zone hostedZone in project.
+	ListRecordSets(ctx context.Context, project, hostedZone string) ([]*gcp.ResourceRecordSet, error)
+}
+
+// client implements Client with the Google Cloud DNS API.
+type client struct {
+	*gcp.Service
+}
+
+// GetZone implements the Client interface.
+func (c client) GetZone(ctx context.Context, project, hostedZone string) error {
+	_, err := c.ManagedZones.Get(project, hostedZone).Context(ctx).Do()
+	return err
+}
+
+// ListRecordSets implements the Client interface.
+func (c client) ListRecordSets(ctx context.Context, project, hostedZone string) ([]*gcp.ResourceRecordSet, error) {
+	var sets []*gcp.ResourceRecordSet
+	err := c.ResourceRecordSets.List(project, hostedZone).Pages(ctx, func(page *gcp.ResourceRecordSetsListResponse) error {
+		sets = append(sets, page.Rrsets...)
+		return nil
+	})
+	return sets, err
+}
@@ -0,0 +1,152 @@
+package clouddns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/test"
+	crequest "github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	gcp "google.golang.org/api/dns/v1"
+)
+
+type fakeGCPClient struct{}
+
+func (fakeGCPClient) GetZone(_ context.Context, project, hostedZone string) error {
+	if hostedZone == "nosuch" {
+		return errors.New("zone not found")
+	}
+	return nil
+}
+
+func rrs(name, typ string, rrdatas ...string) *gcp.ResourceRecordSet {
+	return &gcp.ResourceRecordSet{Name: name, Type: typ, Ttl: 300, Rrdatas: rrdatas}
+}
+
+func (fakeGCPClient) ListRecordSets(_ context.Context, project, hostedZone string) ([]*gcp.ResourceRecordSet, error) {
+	if hostedZone == "bad" {
+		return nil, errors.New("bad. zone is bad")
+	}
+	sets := map[string][]*gcp.ResourceRecordSet{
+		"sample-zone-1": {
+			rrs("example.org.", "SOA", "ns-cloud-e1.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 3600 259200 300"),
+			rrs("example.org.", "A", "1.2.3.4"),
+			rrs("example.org.", "AAAA", "2001:db8::1"),
+			rrs("example.org.", "MX", "10 mail.example.org."),
+			rrs("example.org.", "TXT", `"v=spf1 -all"`),
+			rrs("www.example.org.", "CNAME", "example.org."),
+			rrs("_sip._tcp.example.org.", "SRV", "10 20 5060 sip.example.org."),
+			rrs("bad.example.org.", "A", "not-an-ip"),
+		},
+		"sample-zone-2": {
+			rrs("example.org.", "SOA", "ns-cloud-e1.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 3600 259200 300"),
+			rrs("other.example.org.", "A", "3.5.7.9"),
+		},
+		"sample-zone-3": {
+			rrs("example.gov.", "SOA", "ns-cloud-e1.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 3600 259200 300"),
+		},
+	}
+	return sets[hostedZone], nil
+}
+
+func TestCloudDNS(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := New(ctx, fakeGCPClient{}, map[string][]string{"example.org.": {"org-id:nosuch"}}, &upstream.Upstream{}); err == nil {
+		t.Fatal("Expected error for non-existing zone")
+	}
+
+	r, err := New(ctx, fakeGCPClient{}, map[string][]string{"bad.": {"org-id:bad"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	if err = r.Run(ctx); err == nil {
+		t.Fatalf("Expected errors for zone bad.")
+	}
+
+	r, err = New(ctx, fakeGCPClient{}, map[string][]string{
+		"example.org.": {"org-id:sample-zone-1", "org-id:sample-zone-2"},
+		"example.gov.": {"org-id:sample-zone-3"},
+	}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	r.Fall = fall.Zero
+	r.Fall.SetZonesFromArgs([]string{"example.gov."})
+	r.Next = test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		state := crequest.Request{W: w, Req: r}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A(state.QName() + " 300 IN A 2.4.6.8")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Failed to initialize Cloud DNS: %v", err)
+	}
+
+	soa := test.SOA("example.org. 300 IN SOA ns-cloud-e1.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 3600 259200 300")
+	tests := []test.Case{
+		{
+			Qname: "example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("example.org. 300 IN A 1.2.3.4")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("example.org. 300 IN AAAA 2001:db8::1")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{test.MX("example.org. 300 IN MX 10 mail.example.org.")},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeTXT,
+			Answer: []dns.RR{test.TXT(`example.org. 300 IN TXT "v=spf1 -all"`)},
+		},
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("example.org. 300 IN A 1.2.3.4"),
+				test.CNAME("www.example.org. 300 IN CNAME example.org."),
+			},
+		},
+		{
+			Qname: "_sip._tcp.example.org.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("_sip._tcp.example.org. 300 IN SRV 10 20 5060 sip.example.org.")},
+		},
+		// Stored in the second managed zone.
+		{
+			Qname: "other.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("other.example.org. 300 IN A 3.5.7.9")},
+		},
+		// Invalid record set is skipped.
+		{
+			Qname: "bad.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{soa},
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeSRV,
+			Ns: []dns.RR{soa},
+		},
+		// Fallthrough.
+		{
+			Qname: "www.example.gov.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.gov. 300 IN A 2.4.6.8")},
+		},
+	}
+
+	for i, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(ctx, rec, tc.Msg()); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
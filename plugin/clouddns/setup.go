@@ -0,0 +1,129 @@
+package clouddns
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/mholt/caddy"
+	gcp "google.golang.org/api/dns/v1"
+	"google.golang.org/api/option"
+)
+
+var log = clog.NewWithPlugin("clouddns")
+
+func init() {
+	caddy.RegisterPlugin("clouddns", caddy.Plugin{
+		ServerType: "dns",
+		Action: func(c *caddy.Controller) error {
+			f := func(ctx context.Context, opts ...option.ClientOption) (Client, error) {
+				s, err := gcp.NewService(ctx, opts...)
+				if err != nil {
+					return nil, err
+				}
+				return client{s}, nil
+			}
+			return setup(c, f)
+		},
+	})
+}
+
+func setup(c *caddy.Controller, f func(ctx context.Context, opts ...option.ClientOption) (Client, error)) error {
+	keyPairs := map[string]struct{}{}
+	keys := map[string][]string{}
+
+	// Without credentials in the Corefile, the Application Default Credentials are
+	// used: the GOOGLE_APPLICATION_CREDENTIALS environment variable, the gcloud
+	// credentials file or the credentials of the GCE instance.
+	var opts []option.ClientOption
+	var fall fall.F
+	refresh := defaultRefresh
+
+	up := upstream.New()
+	for c.Next() {
+		args := c.RemainingArgs()
+
+		for i := 0; i < len(args); i++ {
+			parts := strings.SplitN(args[i], ":", 3)
+			if len(parts) != 3 {
+				return c.Errf("invalid zone '%s'", args[i])
+			}
+			dns, projectName, hostedZone := parts[0], parts[1], parts[2]
+			if dns == "" || projectName == "" || hostedZone == "" {
+				return c.Errf("invalid zone '%s'", args[i])
+			}
+			dns = plugin.Host(dns).Normalize()
+			if _, ok := keyPairs[args[i]]; ok {
+				return c.Errf("conflict zone '%s'", args[i])
+			}
+
+			keyPairs[args[i]] = struct{}{}
+			keys[dns] = append(keys[dns], projectName+":"+hostedZone)
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "upstream":
+				c.RemainingArgs() // eats args
+			case "credentials":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				opts = append(opts, option.WithCredentialsFile(c.Val()))
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+			case "refresh":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return c.Errf("unable to parse refresh duration '%s': %v", c.Val(), err)
+				}
+				if d <= 0 {
+					return c.Errf("refresh interval must be greater than 0: %s", c.Val())
+				}
+				refresh = d
+			case "fallthrough":
+				fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := f(ctx, opts...)
+	if err != nil {
+		cancel()
+		return c.Errf("failed to create Google Cloud DNS client: %v", err)
+	}
+	h, err := New(ctx, client, keys, up)
+	if err != nil {
+		cancel()
+		return c.Errf("failed to create Cloud DNS plugin: %v", err)
+	}
+	h.Fall = fall
+	h.refresh = refresh
+	if err := h.Run(ctx); err != nil {
+		cancel()
+		return c.Errf("failed to initialize Cloud DNS plugin: %v", err)
+	}
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		h.Next = next
+		return h
+	})
+
+	return nil
+}
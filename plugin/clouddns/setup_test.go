@@ -0,0 +1,51 @@
+package clouddns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"google.golang.org/api/option"
+)
+
+func TestSetupCloudDNS(t *testing.T) {
+	f := func(ctx context.Context, opts ...option.ClientOption) (Client, error) {
+		return fakeGCPClient{}, nil
+	}
+
+	tests := []struct {
+		body          string
+		expectedError bool
+	}{
+		{`clouddns`, false},
+		{`clouddns :`, true},
+		{`clouddns ::`, true},
+		{`clouddns example.org.:example-project:zone-name`, false},
+		{`clouddns example.org.:example-project:zone-name { }`, false},
+		{`clouddns example.org.:example-project: { }`, true},
+		{`clouddns example.org.:example-project:zone-name example.org.:example-project:zone-name { }`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    upstream
+    refresh 5m
+    credentials /path/to/credentials.json
+    fallthrough
+}`, false},
+		{`clouddns example.org.:example-project:zone-name {
+    credentials
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh 0s
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    wat
+}`, true},
+		{`clouddns example.org.:example-project:nosuch`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.body)
+		if err := setup(c, f); (err == nil) == test.expectedError {
+			t.Errorf("Test %d: unexpected errors: %v", i, err)
+		}
+	}
+}
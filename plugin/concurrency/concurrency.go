@@ -0,0 +1,11 @@
+// Package concurrency limits the number of queries a server handles concurrently.
+package concurrency
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("concurrency", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
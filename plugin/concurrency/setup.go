@@ -0,0 +1,56 @@
+package concurrency
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.MaxConcurrent > 0 {
+		return plugin.Error("concurrency", c.Errf("concurrency already configured for this server instance"))
+	}
+
+	max, rcode, err := parse(c)
+	if err != nil {
+		return plugin.Error("concurrency", err)
+	}
+	config.MaxConcurrent = max
+	config.ConcurrencyRcode = rcode
+	return nil
+}
+
+func parse(c *caddy.Controller) (max, rcode int, err error) {
+	rcode = dns.RcodeRefused
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) < 1 || len(args) > 2 {
+			return 0, 0, c.ArgErr()
+		}
+		max, err = strconv.Atoi(args[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		if max < 1 {
+			return 0, 0, fmt.Errorf("max must be at least 1: %d", max)
+		}
+		if len(args) == 2 {
+			switch args[1] {
+			case "REFUSED":
+				rcode = dns.RcodeRefused
+			case "SERVFAIL":
+				rcode = dns.RcodeServerFailure
+			default:
+				return 0, 0, fmt.Errorf("rcode must be REFUSED or SERVFAIL: %s", args[1])
+			}
+		}
+	}
+	return max, rcode, nil
+}
@@ -0,0 +1,47 @@
+package concurrency
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config        string
+		expectedMax   int
+		expectedRcode int
+		failing       bool
+	}{
+		{`concurrency 1000`, 1000, dns.RcodeRefused, false},
+		{`concurrency 10 REFUSED`, 10, dns.RcodeRefused, false},
+		{`concurrency 10 SERVFAIL`, 10, dns.RcodeServerFailure, false},
+		{`concurrency`, 0, 0, true},
+		{`concurrency 0`, 0, 0, true},
+		{`concurrency many`, 0, 0, true},
+		{`concurrency 10 NXDOMAIN`, 0, 0, true},
+		{`concurrency 10 REFUSED 20`, 0, 0, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not", i)
+		}
+		config := dnsserver.GetConfig(c)
+		if config.MaxConcurrent != test.expectedMax {
+			t.Errorf("Test %d: expected max %d, got %d", i, test.expectedMax, config.MaxConcurrent)
+		}
+		if config.ConcurrencyRcode != test.expectedRcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, test.expectedRcode, config.ConcurrencyRcode)
+		}
+	}
+}
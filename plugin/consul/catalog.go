@@ -0,0 +1,180 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// instance is an instance of a service in the Consul catalog.
+type instance struct {
+	ID      string // ServiceID, sanitized to be usable as a DNS label
+	Service string
+	Node    string
+	Address string // ServiceAddress, or the address of the node when that is empty
+	Port    int
+	Tags    []string
+}
+
+// catalog is a copy of the Consul catalog.
+type catalog struct {
+	index    uint64
+	services map[string][]instance // keyed by service name, sanitized with label
+	nodes    map[string]string     // node name (sanitized with label) to address
+}
+
+// catalogService is an element of the response of /v1/catalog/service/<service>.
+type catalogService struct {
+	ID             string
+	Node           string
+	Address        string
+	ServiceID      string
+	ServiceName    string
+	ServiceTags    []string
+	ServiceAddress string
+	ServicePort    int
+}
+
+// get does a GET request for path and decodes the JSON response into v. If index is not zero
+// the request is a blocking query that returns when the index changes or wait has passed. The
+// X-Consul-Index of the response is returned.
+func (c *Consul) get(ctx context.Context, path string, index uint64, v interface{}) (uint64, error) {
+	q := url.Values{}
+	if c.datacenter != "" {
+		q.Set("dc", c.datacenter)
+	}
+	timeout := c.timeout
+	if index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", fmt.Sprintf("%ds", int(c.wait/time.Second)))
+		// Consul adds up to wait/16 of jitter to blocking queries.
+		timeout += c.wait + c.wait/16
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, c.address+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %q for %s", resp.Status, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, err
+	}
+
+	idx, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid X-Consul-Index for %s: %v", path, err)
+	}
+	return idx, nil
+}
+
+// update waits for the catalog to change from index and then loads it. It returns the new index.
+// When index is zero the catalog is loaded right away.
+func (c *Consul) update(ctx context.Context, index uint64) (uint64, error) {
+	services := map[string][]string{}
+	idx, err := c.get(ctx, "/v1/catalog/services", index, &services)
+	if err != nil {
+		return index, err
+	}
+	if idx == index {
+		// Timed out, nothing changed.
+		return idx, nil
+	}
+
+	cat := &catalog{index: idx, services: make(map[string][]instance), nodes: make(map[string]string)}
+	for name := range services {
+		entries := []catalogService{}
+		if _, err := c.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), 0, &entries); err != nil {
+			return index, err
+		}
+		for _, e := range entries {
+			addr := e.ServiceAddress
+			if addr == "" {
+				addr = e.Address
+			}
+			key := label(e.ServiceName)
+			node := label(e.Node)
+			cat.services[key] = append(cat.services[key], instance{
+				ID:      label(e.ServiceID),
+				Service: key,
+				Node:    node,
+				Address: addr,
+				Port:    e.ServicePort,
+				Tags:    e.ServiceTags,
+			})
+			cat.nodes[node] = e.Address
+		}
+	}
+
+	c.Lock()
+	c.catalog = cat
+	c.Unlock()
+
+	// The index may go backwards, in which case we need to start over (see the Consul docs on blocking queries).
+	if idx < index {
+		return 0, nil
+	}
+	return idx, nil
+}
+
+// load loads the catalog, giving up after c.timeout so an unreachable Consul doesn't hold up startup.
+func (c *Consul) load(ctx context.Context) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.update(ctx, 0)
+}
+
+// watch keeps the catalog up to date with blocking queries until ctx is canceled.
+func (c *Consul) watch(ctx context.Context, index uint64) {
+	for {
+		idx, err := c.update(ctx, index)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if err != nil {
+			log.Warningf("Failed to update the catalog from %s: %s", c.address, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+		index = idx
+	}
+}
+
+// label returns s lower cased with all characters that are not valid in a DNS label replaced by a dash.
+func label(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, s)
+}
+
+const retryInterval = 5 * time.Second
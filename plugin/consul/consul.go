@@ -0,0 +1,191 @@
+// Package consul implements a plugin that serves services and nodes from the Consul catalog.
+package consul
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Consul is a plugin that serves the Consul catalog. Services are served as
+// [TAG|ID.]SERVICE.service.ZONE and nodes as NODE.node.ZONE.
+type Consul struct {
+	Next       plugin.Handler
+	Zones      []string
+	Fall       fall.F
+	Upstream   *upstream.Upstream
+	TransferTo []string
+
+	address    string // address of the Consul HTTP API
+	token      string
+	datacenter string
+	ttl        uint32
+	wait       time.Duration // wait time for blocking queries
+	timeout    time.Duration // timeout for requests, on top of wait for blocking queries
+	client     *http.Client
+
+	sync.RWMutex
+	catalog *catalog
+}
+
+var errKeyNotFound = errors.New("key not found")
+
+// New returns a new Consul for the API on address.
+func New(zones []string, address string) *Consul {
+	return &Consul{
+		Zones:   zones,
+		address: address,
+		ttl:     defaultTTL,
+		wait:    defaultWait,
+		timeout: defaultTimeout,
+		client:  &http.Client{},
+		catalog: &catalog{services: map[string][]instance{}, nodes: map[string]string{}},
+	}
+}
+
+// Services implements the ServiceBackend interface.
+func (c *Consul) Services(ctx context.Context, state request.Request, exact bool, opt plugin.Options) (services []msg.Service, err error) {
+	services, err = c.Records(ctx, state, exact)
+	if err != nil {
+		return
+	}
+
+	services = msg.Group(services)
+	return
+}
+
+// Reverse implements the ServiceBackend interface.
+func (c *Consul) Reverse(ctx context.Context, state request.Request, exact bool, opt plugin.Options) (services []msg.Service, err error) {
+	ip := dnsutil.ExtractAddressFromReverse(state.Name())
+	if ip == "" {
+		return nil, errKeyNotFound
+	}
+	zone := c.forwardZone()
+	if zone == "" {
+		return nil, errKeyNotFound
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, instances := range c.catalog.services {
+		for _, i := range instances {
+			if i.Address == ip {
+				services = append(services, msg.Service{Host: dnsutil.Join(i.ID, i.Service, "service", zone), TTL: c.ttl})
+			}
+		}
+	}
+	for node, addr := range c.catalog.nodes {
+		if addr == ip {
+			services = append(services, msg.Service{Host: dnsutil.Join(node, "node", zone), TTL: c.ttl})
+		}
+	}
+	if len(services) == 0 {
+		return nil, errKeyNotFound
+	}
+	return services, nil
+}
+
+// Lookup implements the ServiceBackend interface.
+func (c *Consul) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	return c.Upstream.Lookup(ctx, state, name, typ)
+}
+
+// IsNameError implements the ServiceBackend interface.
+func (c *Consul) IsNameError(err error) bool {
+	return err == errKeyNotFound
+}
+
+// Records looks up the services or node for the name in state.
+func (c *Consul) Records(ctx context.Context, state request.Request, exact bool) ([]msg.Service, error) {
+	name := state.Name()
+	zone := plugin.Zones(c.Zones).Matches(name)
+	if zone == "" {
+		return nil, errKeyNotFound
+	}
+	base, _ := dnsutil.TrimZone(name, zone)
+	if base == "" {
+		return nil, nil
+	}
+	labels := dns.SplitDomainName(base)
+	last := len(labels) - 1
+
+	c.RLock()
+	defer c.RUnlock()
+
+	switch {
+	case last == 1 && labels[last] == "node":
+		addr, ok := c.catalog.nodes[labels[0]]
+		if !ok {
+			return nil, errKeyNotFound
+		}
+		return []msg.Service{{Host: addr, TTL: c.ttl, Key: msg.Path(name, "coredns")}}, nil
+
+	case (last == 1 || last == 2) && labels[last] == "service":
+		instances, ok := c.catalog.services[labels[last-1]]
+		if !ok {
+			return nil, errKeyNotFound
+		}
+		services := []msg.Service{}
+		for _, i := range instances {
+			if last == 2 && i.ID != labels[0] && !hasTag(i.Tags, labels[0]) {
+				continue
+			}
+			services = append(services, msg.Service{
+				Host:     i.Address,
+				Port:     i.Port,
+				Priority: 10,
+				TTL:      c.ttl,
+				Key:      msg.Path(dnsutil.Join(i.ID, i.Service, "service", zone), "coredns"),
+			})
+		}
+		if len(services) == 0 {
+			return nil, errKeyNotFound
+		}
+		return services, nil
+
+	case last == 0 && (labels[0] == "service" || labels[0] == "node"):
+		// Empty non-terminal.
+		return nil, nil
+	}
+
+	return nil, errKeyNotFound
+}
+
+// forwardZone returns the first zone that is not a reverse zone.
+func (c *Consul) forwardZone() string {
+	for _, z := range c.Zones {
+		if !dns.IsSubDomain("arpa.", z) {
+			return z
+		}
+	}
+	return ""
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(label(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	defaultTTL     = 30
+	defaultWait    = 5 * time.Minute
+	defaultTimeout = 10 * time.Second
+	defaultAddress = "http://127.0.0.1:8500"
+)
@@ -0,0 +1,264 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// fakeConsul is a stand-in for the Consul catalog HTTP API that supports blocking queries.
+type fakeConsul struct {
+	sync.Mutex
+	index    uint64
+	services []catalogService
+	changed  chan struct{} // closed when the catalog changes
+}
+
+func newFakeConsul(services []catalogService) *fakeConsul {
+	return &fakeConsul{index: 1, services: services, changed: make(chan struct{})}
+}
+
+func (f *fakeConsul) set(services []catalogService) {
+	f.Lock()
+	f.index++
+	f.services = services
+	close(f.changed)
+	f.changed = make(chan struct{})
+	f.Unlock()
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	f.Lock()
+	index, changed := f.index, f.changed
+	f.Unlock()
+
+	// Blocking query: wait for a change when the client is up to date.
+	if idx, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); idx == index {
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+
+	switch {
+	case r.URL.Path == "/v1/catalog/services":
+		services := map[string][]string{}
+		for _, s := range f.services {
+			services[s.ServiceName] = append(services[s.ServiceName], s.ServiceTags...)
+		}
+		json.NewEncoder(w).Encode(services)
+	case strings.HasPrefix(r.URL.Path, "/v1/catalog/service/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/catalog/service/")
+		entries := []catalogService{}
+		for _, s := range f.services {
+			if s.ServiceName == name {
+				entries = append(entries, s)
+			}
+		}
+		json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var catalogServices = []catalogService{
+	{Node: "node1", Address: "10.0.0.1", ServiceID: "web-1", ServiceName: "web", ServiceTags: []string{"primary"}, ServicePort: 80},
+	{Node: "node2", Address: "10.0.0.2", ServiceID: "web-2", ServiceName: "web", ServiceTags: []string{"secondary"}, ServiceAddress: "10.0.1.2", ServicePort: 8080},
+	{Node: "node2", Address: "10.0.0.2", ServiceID: "db:5432", ServiceName: "db", ServicePort: 5432},
+}
+
+func newTestConsul(t *testing.T, f *fakeConsul) (*Consul, func()) {
+	s := httptest.NewServer(f)
+	c := New([]string{"consul."}, s.URL)
+	c.token = "secret"
+	c.wait = time.Second
+	c.Next = test.ErrorHandler()
+	if _, err := c.update(context.TODO(), 0); err != nil {
+		s.Close()
+		t.Fatalf("Failed to load the catalog: %s", err)
+	}
+	return c, s.Close
+}
+
+var dnsTestCases = []test.Case{
+	{
+		Qname: "web.service.consul.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("web.service.consul. 30 IN A 10.0.0.1"),
+			test.A("web.service.consul. 30 IN A 10.0.1.2"),
+		},
+	},
+	{
+		Qname: "primary.web.service.consul.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("primary.web.service.consul. 30 IN A 10.0.0.1")},
+	},
+	{
+		Qname: "web-2.web.service.consul.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("web-2.web.service.consul. 30 IN A 10.0.1.2")},
+	},
+	{
+		Qname: "db.service.consul.", Qtype: dns.TypeSRV,
+		Answer: []dns.RR{test.SRV("db.service.consul. 30 IN SRV 10 100 5432 db-5432.db.service.consul.")},
+		Extra:  []dns.RR{test.A("db-5432.db.service.consul. 30 IN A 10.0.0.2")},
+	},
+	{
+		Qname: "node2.node.consul.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("node2.node.consul. 30 IN A 10.0.0.2")},
+	},
+	{
+		Qname: "2.1.0.10.in-addr.arpa.", Qtype: dns.TypePTR,
+		Answer: []dns.RR{test.PTR("2.1.0.10.in-addr.arpa. 30 IN PTR web-2.web.service.consul.")},
+	},
+	{
+		Qname: "web.service.consul.", Qtype: dns.TypeAAAA,
+		Ns: []dns.RR{test.SOA("consul. 30 IN SOA ns.dns.consul. hostmaster.consul. 3 7200 1800 86400 30")},
+	},
+	{
+		Qname: "nosuch.service.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("consul. 30 IN SOA ns.dns.consul. hostmaster.consul. 3 7200 1800 86400 30")},
+	},
+	{
+		Qname: "tertiary.web.service.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("consul. 30 IN SOA ns.dns.consul. hostmaster.consul. 3 7200 1800 86400 30")},
+	},
+}
+
+func TestConsul(t *testing.T) {
+	c, stop := newTestConsul(t, newFakeConsul(catalogServices))
+	defer stop()
+	c.Zones = append(c.Zones, "in-addr.arpa.")
+
+	ctx := context.TODO()
+	for i, tc := range dnsTestCases {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := c.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestConsulWatch(t *testing.T) {
+	f := newFakeConsul(catalogServices)
+	c, stop := newTestConsul(t, f)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.watch(ctx, f.index)
+
+	f.set(append(catalogServices, catalogService{Node: "node3", Address: "10.0.0.3", ServiceID: "web-3", ServiceName: "web", ServicePort: 80}))
+
+	for i := 0; i < 50; i++ {
+		c.RLock()
+		n := len(c.catalog.services["web"])
+		c.RUnlock()
+		if n == 3 {
+			if s := c.Serial(requestFor("web.service.consul.")); s != 2 {
+				t.Errorf("Expected serial %d, got %d", 2, s)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Catalog was not updated")
+}
+
+func TestConsulToken(t *testing.T) {
+	s := httptest.NewServer(newFakeConsul(catalogServices))
+	defer s.Close()
+
+	c := New([]string{"consul."}, s.URL)
+	if _, err := c.update(context.TODO(), 0); err == nil {
+		t.Fatal("Expected error without a token")
+	}
+}
+
+func TestConsulSanitizeNames(t *testing.T) {
+	c, stop := newTestConsul(t, newFakeConsul([]catalogService{
+		{Node: "Node_1", Address: "10.0.0.1", ServiceID: "api-1", ServiceName: "My_API", ServiceTags: []string{"v1.2"}, ServicePort: 80},
+	}))
+	defer stop()
+
+	tests := []test.Case{
+		{
+			Qname: "my-api.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("my-api.service.consul. 30 IN A 10.0.0.1")},
+		},
+		{
+			Qname: "v1-2.my-api.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("v1-2.my-api.service.consul. 30 IN A 10.0.0.1")},
+		},
+		{
+			Qname: "node-1.node.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("node-1.node.consul. 30 IN A 10.0.0.1")},
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := c.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestConsulLoadTimeout(t *testing.T) {
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-done }))
+	defer s.Close()
+	defer close(done)
+
+	c := New([]string{"consul."}, s.URL)
+	c.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if _, err := c.load(context.TODO()); err == nil {
+		t.Fatal("Expected error from an unresponsive Consul")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected load to give up after %s, took %s", c.timeout, d)
+	}
+}
+
+func requestFor(name string) request.Request {
+	m := new(dns.Msg)
+	m.SetQuestion(name, dns.TypeA)
+	return request.Request{W: &test.ResponseWriter{}, Req: m}
+}
@@ -0,0 +1,140 @@
+package consul
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("consul")
+
+func init() {
+	caddy.RegisterPlugin("consul", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	cs, err := consulParse(c)
+	if err != nil {
+		return plugin.Error("consul", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.OnStartup(func() error {
+		// Load the catalog before we start serving, but don't fail when Consul isn't reachable.
+		index, err := cs.load(ctx)
+		if err != nil {
+			log.Warningf("Failed to load the catalog from %s: %s", cs.address, err)
+		}
+		go cs.watch(ctx, index)
+		return nil
+	})
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		cs.Next = next
+		return cs
+	})
+
+	return nil
+}
+
+func consulParse(c *caddy.Controller) (*Consul, error) {
+	cs := New(nil, defaultAddress)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		cs.Zones = c.RemainingArgs()
+		if len(cs.Zones) == 0 {
+			cs.Zones = make([]string, len(c.ServerBlockKeys))
+			copy(cs.Zones, c.ServerBlockKeys)
+		}
+		for i, str := range cs.Zones {
+			cs.Zones[i] = plugin.Host(str).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "address":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				addr := c.Val()
+				if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+					addr = "http://" + addr
+				}
+				cs.address = strings.TrimSuffix(addr, "/")
+			case "token":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				cs.token = c.Val()
+			case "datacenter":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				cs.datacenter = c.Val()
+			case "ttl":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				t, err := strconv.Atoi(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				if t < 0 || t > 3600 {
+					return nil, c.Errf("ttl must be in range [0, 3600]: %d", t)
+				}
+				cs.ttl = uint32(t)
+			case "wait":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				if d < time.Second || d > 10*time.Minute {
+					return nil, c.Errf("wait must be in range [1s, 10m]: %s", d)
+				}
+				cs.wait = d
+			case "transfer":
+				tos, froms, err := parse.Transfer(c, false)
+				if err != nil {
+					return nil, err
+				}
+				if len(froms) != 0 {
+					return nil, c.Errf("transfer from is not supported with this plugin")
+				}
+				cs.TransferTo = tos
+			case "upstream":
+				c.RemainingArgs() // eat remaining args
+				cs.Upstream = upstream.New()
+			case "fallthrough":
+				cs.Fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return cs, nil
+}
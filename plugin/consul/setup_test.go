@@ -0,0 +1,88 @@
+package consul
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupConsul(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedZones      []string
+		expectedAddress    string
+		expectedTTL        uint32
+		expectedWait       time.Duration
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		// positive
+		{`consul`, false, []string{"consul."}, defaultAddress, defaultTTL, defaultWait, ""},
+		{`consul service.dc1. {
+	address consul.local:8500
+	token secret
+	datacenter dc1
+	ttl 60
+	wait 30s
+}`, false, []string{"service.dc1."}, "http://consul.local:8500", 60, 30 * time.Second, ""},
+		{`consul {
+	address https://consul.local:8501/
+	transfer to 10.0.0.1
+	upstream
+	fallthrough
+}`, false, []string{"consul."}, "https://consul.local:8501", defaultTTL, defaultWait, ""},
+		// negative
+		{`consul {
+	ttl -1
+}`, true, nil, "", 0, 0, "ttl must be in range"},
+		{`consul {
+	wait 1h
+}`, true, nil, "", 0, 0, "wait must be in range"},
+		{`consul {
+	transfer from 10.0.0.1
+}`, true, nil, "", 0, 0, "transfer from"},
+		{`consul {
+	token
+}`, true, nil, "", 0, 0, "Wrong argument count"},
+		{`consul {
+	endpoint localhost:8500
+}`, true, nil, "", 0, 0, "unknown property 'endpoint'"},
+		{`consul
+consul`, true, nil, "", 0, 0, "this plugin"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		c.ServerBlockKeys = []string{"consul."}
+		cs, err := consulParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			continue
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if len(cs.Zones) != len(test.expectedZones) || cs.Zones[0] != test.expectedZones[0] {
+			t.Errorf("Test %d: expected zones %v, got %v", i, test.expectedZones, cs.Zones)
+		}
+		if cs.address != test.expectedAddress {
+			t.Errorf("Test %d: expected address %q, got %q", i, test.expectedAddress, cs.address)
+		}
+		if cs.ttl != test.expectedTTL {
+			t.Errorf("Test %d: expected ttl %d, got %d", i, test.expectedTTL, cs.ttl)
+		}
+		if cs.wait != test.expectedWait {
+			t.Errorf("Test %d: expected wait %s, got %s", i, test.expectedWait, cs.wait)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package consul
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const transferLength = 2000
+
+// Serial implements the Transferer interface. It returns the index of the catalog.
+func (c *Consul) Serial(state request.Request) uint32 {
+	c.RLock()
+	defer c.RUnlock()
+	return uint32(c.catalog.index)
+}
+
+// MinTTL implements the Transferer interface.
+func (c *Consul) MinTTL(state request.Request) uint32 { return c.ttl }
+
+// Transfer implements the Transferer interface.
+func (c *Consul) Transfer(ctx context.Context, state request.Request) (int, error) {
+	if !c.transferAllowed(state) {
+		return dns.RcodeRefused, nil
+	}
+
+	soa, err := plugin.SOA(ctx, c, state.Zone, state, plugin.Options{})
+	if err != nil {
+		return dns.RcodeServerFailure, nil
+	}
+
+	records := append(soa, c.records(state.Zone)...)
+	records = append(records, soa...)
+
+	ch := make(chan *dns.Envelope)
+	tr := new(dns.Transfer)
+	go func(ch chan *dns.Envelope) {
+		j, l := 0, 0
+		log.Infof("Outgoing transfer of %d records of zone %s to %s started", len(records), state.Zone, state.IP())
+		for i, r := range records {
+			l += dns.Len(r)
+			if l > transferLength {
+				ch <- &dns.Envelope{RR: records[j:i]}
+				l = 0
+				j = i
+			}
+		}
+		if j < len(records) {
+			ch <- &dns.Envelope{RR: records[j:]}
+		}
+		close(ch)
+	}(ch)
+
+	tr.Out(state.W, state.Req, ch)
+	// Defer closing to the client
+	state.W.Hijack()
+	return dns.RcodeSuccess, nil
+}
+
+// transferAllowed checks if incoming request for transferring the zone is allowed according to the ACLs.
+func (c *Consul) transferAllowed(state request.Request) bool {
+	for _, t := range c.TransferTo {
+		if t == "*" {
+			return true
+		}
+		// If remote IP matches we accept.
+		to, _, err := net.SplitHostPort(t)
+		if err != nil {
+			continue
+		}
+		if to == state.IP() {
+			return true
+		}
+	}
+	return false
+}
+
+// records returns the records for all services and nodes in zone: address records for the
+// service, for each instance and for each node, and SRV records for each instance.
+func (c *Consul) records(zone string) []dns.RR {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]string, 0, len(c.catalog.services))
+	for name := range c.catalog.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rrs := []dns.RR{}
+	for _, name := range names {
+		svc := dnsutil.Join(name, "service", zone)
+		for _, i := range c.catalog.services[name] {
+			target := dnsutil.Join(i.ID, svc)
+			if rr := c.addressRR(svc, i.Address); rr != nil {
+				rrs = append(rrs, rr, c.addressRR(target, i.Address))
+			}
+			rrs = append(rrs, &dns.SRV{
+				Hdr:      dns.RR_Header{Name: svc, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: c.ttl},
+				Priority: 10, Weight: 100, Port: uint16(i.Port), Target: target,
+			})
+		}
+	}
+
+	nodes := make([]string, 0, len(c.catalog.nodes))
+	for node := range c.catalog.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if rr := c.addressRR(dnsutil.Join(node, "node", zone), c.catalog.nodes[node]); rr != nil {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+// addressRR returns an A or AAAA record for name with address addr, or nil if addr isn't an IP address.
+func (c *Consul) addressRR(name, addr string) dns.RR {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: c.ttl}
+	if ip4 := ip.To4(); ip4 != nil {
+		hdr.Rrtype = dns.TypeA
+		return &dns.A{Hdr: hdr, A: ip4}
+	}
+	hdr.Rrtype = dns.TypeAAAA
+	return &dns.AAAA{Hdr: hdr, AAAA: ip}
+}
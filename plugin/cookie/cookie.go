@@ -0,0 +1,11 @@
+// Package cookie configures the server cookies (RFC 7873) of a server.
+package cookie
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("cookie", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
@@ -0,0 +1,41 @@
+package cookie
+
+import (
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.NoCookies {
+		return plugin.Error("cookie", c.Errf("cookie already configured for this server instance"))
+	}
+
+	off, err := parse(c)
+	if err != nil {
+		return plugin.Error("cookie", err)
+	}
+	config.NoCookies = off
+	return nil
+}
+
+func parse(c *caddy.Controller) (off bool, err error) {
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return false, c.ArgErr()
+		}
+		switch args[0] {
+		case "on":
+			off = false
+		case "off":
+			off = true
+		default:
+			return false, c.Errf("unknown argument '%s'", args[0])
+		}
+	}
+	return off, nil
+}
@@ -0,0 +1,38 @@
+package cookie
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config   string
+		expected bool
+		failing  bool
+	}{
+		{`cookie off`, true, false},
+		{`cookie on`, false, false},
+		{`cookie`, false, true},
+		{`cookie no`, false, true},
+		{`cookie off on`, false, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not", i)
+		}
+		if off := dnsserver.GetConfig(c).NoCookies; off != test.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, test.expected, off)
+		}
+	}
+}
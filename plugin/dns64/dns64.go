@@ -0,0 +1,189 @@
+// Package dns64 implements a plugin that performs DNS64 (RFC 6147): AAAA records are
+// synthesized from A records for names that have no AAAA records.
+package dns64
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Upstream is used to look up the A records (and PTR records) we synthesize from.
+type Upstream interface {
+	Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
+}
+
+// DNS64 synthesizes AAAA records from A records using Prefix.
+type DNS64 struct {
+	Next     plugin.Handler
+	Prefix   *net.IPNet // a /96 prefix
+	Upstream Upstream
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (d *DNS64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	switch state.QType() {
+	case dns.TypeAAAA:
+		if state.QClass() != dns.ClassINET {
+			break
+		}
+		// A validating client that sets DO and CD would reject the synthesized (unsigned) records,
+		// don't synthesize for it (RFC 6147, section 5.5).
+		if state.Do() && r.CheckingDisabled {
+			break
+		}
+		rw := &ResponseWriter{ResponseWriter: w, DNS64: d, ctx: ctx, state: state}
+		return plugin.NextOrFailure(d.Name(), d.Next, ctx, rw, r)
+
+	case dns.TypePTR:
+		if ip := d.extract(state.Name()); ip != nil {
+			return d.servePTR(ctx, state, ip)
+		}
+	}
+
+	return plugin.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+}
+
+// Name implements the Handler interface.
+func (d *DNS64) Name() string { return "dns64" }
+
+// extract returns the IPv4 address embedded in the reverse name, or nil if name is not
+// a reverse name in our prefix.
+func (d *DNS64) extract(name string) net.IP {
+	addr := dnsutil.ExtractAddressFromReverse(name)
+	if addr == "" {
+		return nil
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil || !d.Prefix.Contains(ip) {
+		return nil
+	}
+	return ip[12:16]
+}
+
+// servePTR answers PTR queries for synthesized addresses with a CNAME to the reverse name of
+// the IPv4 address (RFC 6147, section 5.3.1), followed by the answer for that name.
+func (d *DNS64) servePTR(ctx context.Context, state request.Request, ip net.IP) (int, error) {
+	target, _ := dns.ReverseAddr(ip.String())
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Answer = []dns.RR{&dns.CNAME{
+		Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: ptrTTL},
+		Target: target,
+	}}
+
+	if up, err := d.Upstream.Lookup(ctx, state, target, dns.TypePTR); err == nil && up != nil {
+		m.Rcode = up.Rcode
+		m.Answer = append(m.Answer, up.Answer...)
+		m.Ns = up.Ns
+	}
+
+	state.SizeAndDo(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// synthesize returns a reply to the AAAA query in state made from the A records for the same
+// name, or nil when there are none. res is the original (empty) AAAA reply.
+func (d *DNS64) synthesize(ctx context.Context, state request.Request, res *dns.Msg) *dns.Msg {
+	up, err := d.Upstream.Lookup(ctx, state, state.Name(), dns.TypeA)
+	if err != nil || up == nil || up.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+
+	// The TTL is capped to the negative caching TTL of the AAAA reply (RFC 6147, section 5.1.7).
+	maxTTL := uint32(0)
+	for _, rr := range res.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			maxTTL = soa.Minttl
+			if soa.Hdr.Ttl < maxTTL {
+				maxTTL = soa.Hdr.Ttl
+			}
+		}
+	}
+
+	answer := make([]dns.RR, 0, len(up.Answer))
+	synthesized := false
+	for _, rr := range up.Answer {
+		switch x := rr.(type) {
+		case *dns.CNAME:
+			answer = append(answer, x)
+		case *dns.A:
+			aaaa := &dns.AAAA{Hdr: x.Hdr, AAAA: d.to6(x.A)}
+			aaaa.Hdr.Rrtype = dns.TypeAAAA
+			if maxTTL > 0 && aaaa.Hdr.Ttl > maxTTL {
+				aaaa.Hdr.Ttl = maxTTL
+			}
+			answer = append(answer, aaaa)
+			synthesized = true
+		}
+	}
+	if !synthesized {
+		return nil
+	}
+
+	m := res.Copy()
+	m.Answer = answer
+	m.Ns = nil
+	m.AuthenticatedData = false
+	return m
+}
+
+// to6 maps the IPv4 address ip into our prefix.
+func (d *DNS64) to6(ip net.IP) net.IP {
+	ip6 := make(net.IP, net.IPv6len)
+	copy(ip6, d.Prefix.IP.To16())
+	copy(ip6[12:], ip.To4())
+	return ip6
+}
+
+// ResponseWriter is a response writer that synthesizes AAAA records when the reply to the AAAA query
+// is empty.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	*DNS64
+
+	ctx   context.Context
+	state request.Request
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if res.Rcode != dns.RcodeSuccess || hasAAAA(res) {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	m := w.synthesize(w.ctx, w.state, res)
+	if m == nil {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	requestsTranslated.WithLabelValues(metrics.WithServer(w.ctx)).Inc()
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	log.Warning("DNS64 called with Write: not translating reply")
+	return w.ResponseWriter.Write(buf)
+}
+
+func hasAAAA(res *dns.Msg) bool {
+	for _, rr := range res.Answer {
+		if rr.Header().Rrtype == dns.TypeAAAA {
+			return true
+		}
+	}
+	return false
+}
+
+const ptrTTL = 300 // TTL of the CNAME we return for PTR queries in the prefix
@@ -0,0 +1,178 @@
+package dns64
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// fakeUpstream answers from a fixed set of records.
+type fakeUpstream map[string][]dns.RR
+
+func (f fakeUpstream) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, typ)
+	m.Response = true
+	for _, rr := range f[name] {
+		if rr.Header().Rrtype == typ || rr.Header().Rrtype == dns.TypeCNAME {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	return m, nil
+}
+
+// next is the rest of the chain: it has AAAA records for ipv6.example.org. and returns NODATA
+// for everything else.
+func next() test.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "ipv6.example.org.":
+			m.Answer = []dns.RR{test.AAAA("ipv6.example.org. 300 IN AAAA 2001:db8::1")}
+		case "nxdomain.example.org.":
+			m.Rcode = dns.RcodeNameError
+			fallthrough
+		default:
+			m.Ns = []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")}
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+}
+
+func TestDNS64(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR(defaultPrefix)
+	d := &DNS64{
+		Next:   next(),
+		Prefix: prefix,
+		Upstream: fakeUpstream{
+			"ipv4.example.org.":       {test.A("ipv4.example.org. 300 IN A 192.0.2.1")},
+			"ipv6.example.org.":       {test.A("ipv6.example.org. 300 IN A 192.0.2.2")},
+			"alias.example.org.":      {test.CNAME("alias.example.org. 300 IN CNAME ipv4.example.org."), test.A("ipv4.example.org. 60 IN A 192.0.2.1")},
+			"1.2.0.192.in-addr.arpa.": {test.PTR("1.2.0.192.in-addr.arpa. 300 IN PTR ipv4.example.org.")},
+		},
+	}
+
+	tests := []test.Case{
+		{
+			Qname: "ipv4.example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("ipv4.example.org. 120 IN AAAA 64:ff9b::c000:201")},
+		},
+		{
+			Qname: "alias.example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 300 IN CNAME ipv4.example.org."),
+				test.AAAA("ipv4.example.org. 60 IN AAAA 64:ff9b::c000:201"),
+			},
+		},
+		{
+			// Has AAAA records, nothing to synthesize.
+			Qname: "ipv6.example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("ipv6.example.org. 300 IN AAAA 2001:db8::1")},
+		},
+		{
+			// No A records either, the original reply is returned.
+			Qname: "none.example.org.", Qtype: dns.TypeAAAA,
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")},
+		},
+		{
+			Qname: "nxdomain.example.org.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")},
+		},
+		{
+			// Only AAAA queries are synthesized.
+			Qname: "ipv4.example.org.", Qtype: dns.TypeTXT,
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")},
+		},
+		{
+			Qname: "1.0.2.0.0.0.0.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.b.9.f.f.4.6.0.0.ip6.arpa.", Qtype: dns.TypePTR,
+			Answer: []dns.RR{
+				test.CNAME("1.0.2.0.0.0.0.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.b.9.f.f.4.6.0.0.ip6.arpa. 300 IN CNAME 1.2.0.192.in-addr.arpa."),
+				test.PTR("1.2.0.192.in-addr.arpa. 300 IN PTR ipv4.example.org."),
+			},
+		},
+		{
+			// Not in the prefix.
+			Qname: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", Qtype: dns.TypePTR,
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")},
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestDNS64CheckingDisabled(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR(defaultPrefix)
+	d := &DNS64{
+		Next:     next(),
+		Prefix:   prefix,
+		Upstream: fakeUpstream{"ipv4.example.org.": {test.A("ipv4.example.org. 300 IN A 192.0.2.1")}},
+	}
+
+	tests := []struct {
+		do, cd    bool
+		synthesis bool
+	}{
+		{false, false, true},
+		{true, false, true},
+		{false, true, true},
+		{true, true, false},
+	}
+
+	for i, tc := range tests {
+		m := test.Case{Qname: "ipv4.example.org.", Qtype: dns.TypeAAAA, Do: tc.do}.Msg()
+		m.CheckingDisabled = tc.cd
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if got := len(rec.Msg.Answer) > 0; got != tc.synthesis {
+			t.Errorf("Test %d: expected synthesis to be %t, got %t", i, tc.synthesis, got)
+		}
+		if !tc.synthesis && len(rec.Msg.Ns) != 1 {
+			t.Errorf("Test %d: expected the original reply, got %v", i, rec.Msg)
+		}
+	}
+}
+
+func TestDNS64OtherPrefix(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("2001:db8:64::/96")
+	d := &DNS64{
+		Next:     next(),
+		Prefix:   prefix,
+		Upstream: fakeUpstream{"ipv4.example.org.": {test.A("ipv4.example.org. 60 IN A 198.51.100.10")}},
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("ipv4.example.org.", dns.TypeAAAA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	d.ServeDNS(context.TODO(), rec, m)
+
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("Expected 1 answer, got %d", len(rec.Msg.Answer))
+	}
+	if got := rec.Msg.Answer[0].(*dns.AAAA).AAAA.String(); got != "2001:db8:64::c633:640a" {
+		t.Errorf("Expected %s, got %s", "2001:db8:64::c633:640a", got)
+	}
+}
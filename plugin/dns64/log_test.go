@@ -0,0 +1,5 @@
+package dns64
+
+import clog "github.com/coredns/coredns/plugin/pkg/log"
+
+func init() { clog.Discard() }
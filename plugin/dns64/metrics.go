@@ -0,0 +1,15 @@
+package dns64
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestsTranslated is the number of AAAA replies we synthesized.
+var requestsTranslated = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dns64",
+	Name:      "requests_translated_total",
+	Help:      "Counter of AAAA replies that were synthesized from A records.",
+}, []string{"server"})
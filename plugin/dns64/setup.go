@@ -0,0 +1,80 @@
+package dns64
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("dns64")
+
+func init() {
+	caddy.RegisterPlugin("dns64", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	prefix, err := dns64Parse(c)
+	if err != nil {
+		return plugin.Error("dns64", err)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, requestsTranslated)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return &DNS64{Next: next, Prefix: prefix, Upstream: upstream.New()}
+	})
+
+	return nil
+}
+
+func dns64Parse(c *caddy.Controller) (*net.IPNet, error) {
+	_, prefix, _ := net.ParseCIDR(defaultPrefix)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			ip, n, err := net.ParseCIDR(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if ip.To4() != nil {
+				return nil, fmt.Errorf("prefix is not an IPv6 prefix: %s", args[0])
+			}
+			if ones, _ := n.Mask.Size(); ones != 96 {
+				return nil, fmt.Errorf("prefix must be a /96: %s", args[0])
+			}
+			prefix = n
+		default:
+			return nil, c.ArgErr()
+		}
+
+		if c.NextBlock() {
+			return nil, c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+	return prefix, nil
+}
+
+// defaultPrefix is the well-known prefix from RFC 6052.
+const defaultPrefix = "64:ff9b::/96"
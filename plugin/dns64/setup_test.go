@@ -0,0 +1,45 @@
+package dns64
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		prefix    string
+	}{
+		{`dns64`, false, "64:ff9b::/96"},
+		{`dns64 2001:db8:64::/96`, false, "2001:db8:64::/96"},
+		{`dns64 2001:db8::/64`, true, ""},
+		{`dns64 10.0.0.0/8`, true, ""},
+		{`dns64 foo`, true, ""},
+		{`dns64 64:ff9b::/96 2001:db8:64::/96`, true, ""},
+		{`dns64 {
+			foo
+		}`, true, ""},
+		{`dns64
+		dns64`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		prefix, err := dns64Parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+		if prefix.String() != test.prefix {
+			t.Errorf("Test %d: expected prefix %s, got %s", i, test.prefix, prefix)
+		}
+	}
+}
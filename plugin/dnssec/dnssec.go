@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	"github.com/coredns/coredns/request"
@@ -58,39 +59,62 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 		ttl := req.Ns[0].Header().Ttl
 
+		var signErr error
 		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			signErr = err
 		}
 		if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			signErr = err
 		}
 		if len(req.Ns) > 1 { // actually added nsec and sigs, reset the rcode
 			req.Rcode = dns.RcodeSuccess
 		}
+		signFailed(req, signErr)
 		return req
 	}
 
+	var signErr error
 	for _, r := range rrSets(req.Answer) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Answer = append(req.Answer, sigs...)
+		} else {
+			signErr = err
 		}
 	}
 	for _, r := range rrSets(req.Ns) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			signErr = err
 		}
 	}
 	for _, r := range rrSets(req.Extra) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Extra = append(req.Extra, sigs...)
+		} else {
+			signErr = err
 		}
 	}
+	signFailed(req, signErr)
 	return req
 }
 
+// signFailed adds an Extended DNS Error to m when err is not nil, the client then knows why records
+// are missing their signatures.
+func signFailed(m *dns.Msg, err error) {
+	if err == nil {
+		return
+	}
+	edns.AddExtendedError(m, edns.ExtendedError{InfoCode: edns.ExtendedErrorCodeOther, ExtraText: "failed to sign: " + err.Error()})
+}
+
 func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32, server string) ([]dns.RR, error) {
 	k := hash(rrs)
 	sgs, ok := d.get(k, server)
@@ -0,0 +1,102 @@
+package erratic
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+)
+
+var uniqAdmin = uniq.New()
+
+// admin is an HTTP API to enable and disable scenarios at runtime. All erratic plugins configured
+// with the same address share one admin; scenarios with the same name are toggled together.
+type admin struct {
+	*http.ServeMux
+
+	sync.RWMutex
+	scenarios map[string][]registered
+}
+
+// registered is a scenario of an erratic plugin, together with the start of that plugin's time windows.
+type registered struct {
+	*scenario
+	start time.Time
+}
+
+func newAdmin() *admin {
+	a := &admin{ServeMux: http.NewServeMux(), scenarios: make(map[string][]registered)}
+	a.HandleFunc("/erratic", a.list)
+	a.HandleFunc("/erratic/", a.serveHTTP)
+	return a
+}
+
+// add registers the scenarios of e.
+func (a *admin) add(e *Erratic) {
+	a.Lock()
+	for _, s := range e.scenarios {
+		a.scenarios[s.name] = append(a.scenarios[s.name], registered{s, e.start})
+	}
+	a.Unlock()
+}
+
+// list lists the scenarios and their state: "enabled" or "disabled", and "active" when the
+// scenario is enabled and inside its time window.
+func (a *admin) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	a.RLock()
+	defer a.RUnlock()
+
+	names := make([]string, 0, len(a.scenarios))
+	for n := range a.scenarios {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, n := range names {
+		s := a.scenarios[n][0]
+		state := "disabled"
+		if s.active(s.start, now) {
+			state = "active"
+		} else if s.enabled() {
+			state = "enabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", n, state)
+	}
+}
+
+// serveHTTP handles /erratic/<scenario>/{enable,disable}.
+func (a *admin) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	el := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/erratic/"), "/", 2)
+	if len(el) != 2 || (el[1] != "enable" && el[1] != "disable") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.RLock()
+	scenarios, ok := a.scenarios[el[0]]
+	a.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("no scenario %q", el[0]), http.StatusNotFound)
+		return
+	}
+
+	for _, s := range scenarios {
+		s.enable(el[1] == "enable")
+	}
+	log.Infof("Scenario %q %sd", el[0], el[1])
+}
+
+const defAdminAddr = "localhost:8183"
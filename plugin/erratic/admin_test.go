@@ -0,0 +1,77 @@
+package erratic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/adminapi"
+)
+
+func TestAdmin(t *testing.T) {
+	e := &Erratic{start: time.Now(), scenarios: []*scenario{{name: "outage"}, {name: "slow"}}}
+	e.scenarios[1].enable(false)
+
+	a := newAdmin()
+	a.add(e)
+	l := adminapi.New("localhost:0", a)
+	if err := l.Start(); err != nil {
+		t.Fatalf("Unable to startup the admin server: %v", err)
+	}
+	defer l.Stop()
+
+	base := fmt.Sprintf("http://%s/erratic", l.Addr().String())
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		contains string
+	}{
+		{http.MethodGet, "", http.StatusOK, "outage\tactive\nslow\tdisabled\n"},
+		{http.MethodGet, "/outage/disable", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/outage/explode", http.StatusNotFound, ""},
+		{http.MethodPost, "/none/disable", http.StatusNotFound, ""},
+		{http.MethodPost, "/outage/disable", http.StatusOK, ""},
+		{http.MethodPost, "/slow/enable", http.StatusOK, ""},
+		{http.MethodGet, "", http.StatusOK, "outage\tdisabled\nslow\tactive\n"},
+	}
+
+	for i, tc := range tests {
+		req, _ := http.NewRequest(tc.method, base+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: unable to query %s: %v", i, tc.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code {
+			t.Errorf("Test %d: expected status code %d, got %d", i, tc.code, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), tc.contains) {
+			t.Errorf("Test %d: expected body to contain %q, got %q", i, tc.contains, body)
+		}
+	}
+}
+
+func TestAdminStart(t *testing.T) {
+	// Each erratic plugin keeps its own time windows, adding another one doesn't move them.
+	now := time.Now()
+	e1 := &Erratic{start: now, scenarios: []*scenario{{name: "flap", period: time.Minute, duration: 30 * time.Second}}}
+	e2 := &Erratic{start: now.Add(-45 * time.Second), scenarios: []*scenario{{name: "flap", period: time.Minute, duration: 30 * time.Second}}}
+
+	a := newAdmin()
+	a.add(e1)
+	a.add(e2)
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/erratic", nil))
+	if x := rec.Body.String(); x != "flap\tactive\n" {
+		t.Errorf("Expected %q, got %q", "flap\tactive\n", x)
+	}
+}
@@ -21,6 +21,10 @@ type Erratic struct {
 	truncate uint64
 	large    bool // undocumented feature; return large responses for A request (>512B, to test compression).
 
+	scenarios []*scenario
+	start     time.Time // start of the time windows of the scenarios
+	admin     string    // address of the admin API
+
 	q uint64 // counter of queries
 }
 
@@ -30,6 +34,7 @@ func (e *Erratic) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	drop := false
 	delay := false
 	trunc := false
+	duration := e.duration
 
 	queryNr := atomic.LoadUint64(&e.q)
 	atomic.AddUint64(&e.q, 1)
@@ -44,6 +49,15 @@ func (e *Erratic) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		trunc = true
 	}
 
+	// A matching rule from a scenario overrides the above.
+	rule := e.match(state)
+	if rule != nil {
+		drop = rule.action == actDrop
+		delay = rule.action == actDelay
+		duration = rule.delay
+		trunc = false
+	}
+
 	m := new(dns.Msg)
 	m.SetReply(r)
 	m.Authoritative = true
@@ -72,18 +86,25 @@ func (e *Erratic) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			return 0, nil
 		}
 		if delay {
-			time.Sleep(e.duration)
+			time.Sleep(duration)
+		}
+		if rule != nil && rule.action == actTruncate {
+			trunc = true
 		}
 
 		xfr(state, trunc)
 		return 0, nil
 
 	default:
+		if rule != nil {
+			// Reply with an empty answer (or what the rule makes of it).
+			break
+		}
 		if drop {
 			return 0, nil
 		}
 		if delay {
-			time.Sleep(e.duration)
+			time.Sleep(duration)
 		}
 		// coredns will return error.
 		return dns.RcodeServerFailure, nil
@@ -94,10 +115,20 @@ func (e *Erratic) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	}
 
 	if delay {
-		time.Sleep(e.duration)
+		time.Sleep(duration)
 	}
 
-	w.WriteMsg(m)
+	replies := 1
+	if rule != nil {
+		rule.apply(m)
+		if rule.action == actDuplicate {
+			replies += rule.count
+		}
+	}
+
+	for i := 0; i < replies; i++ {
+		w.WriteMsg(m)
+	}
 
 	return 0, nil
 }
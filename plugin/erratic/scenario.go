@@ -0,0 +1,135 @@
+package erratic
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// scenario is a named set of rules that can be enabled and disabled at runtime.
+type scenario struct {
+	name  string
+	rules []rule
+
+	// If period is not zero the scenario is only active during the first duration of each period.
+	period   time.Duration
+	duration time.Duration
+
+	disabled int32 // accessed atomically
+}
+
+// action is what a rule does to a query.
+type action int
+
+const (
+	actRcode     action = iota // reply with a specific rcode
+	actDrop                    // don't reply
+	actDelay                   // delay the reply
+	actTruncate                // set the TC bit
+	actCorruptID               // reply with a different message ID
+	actDuplicate               // send the reply more than once
+)
+
+func (a action) String() string {
+	for s, act := range actions {
+		if act == a {
+			return s
+		}
+	}
+	return "unknown"
+}
+
+var actions = map[string]action{
+	"rcode":      actRcode,
+	"drop":       actDrop,
+	"delay":      actDelay,
+	"truncate":   actTruncate,
+	"corrupt_id": actCorruptID,
+	"duplicate":  actDuplicate,
+}
+
+// rule applies action to queries for name (and names below it) with type qtype.
+type rule struct {
+	name   string
+	qtype  uint16 // dns.TypeANY matches all types
+	action action
+
+	rcode int           // for actRcode
+	delay time.Duration // for actDelay
+	count int           // for actDuplicate, the number of extra replies
+}
+
+func (r rule) match(qname string, qtype uint16) bool {
+	if r.qtype != dns.TypeANY && r.qtype != qtype {
+		return false
+	}
+	return dns.IsSubDomain(r.name, qname)
+}
+
+// apply modifies the reply m according to the rule.
+func (r rule) apply(m *dns.Msg) {
+	switch r.action {
+	case actRcode:
+		m.Rcode = r.rcode
+		m.Answer = nil
+	case actTruncate:
+		m.Truncated = true
+	case actCorruptID:
+		m.Id++
+	}
+}
+
+// active returns true if s is enabled and inside its time window. Windows start at start.
+func (s *scenario) active(start, now time.Time) bool {
+	if !s.enabled() {
+		return false
+	}
+	if s.period == 0 {
+		return true
+	}
+	return now.Sub(start)%s.period < s.duration
+}
+
+func (s *scenario) enabled() bool { return atomic.LoadInt32(&s.disabled) == 0 }
+
+func (s *scenario) enable(on bool) {
+	if on {
+		atomic.StoreInt32(&s.disabled, 0)
+		return
+	}
+	atomic.StoreInt32(&s.disabled, 1)
+}
+
+// match returns the first rule of the active scenarios that matches the query in state, or nil
+// if there is none.
+func (e *Erratic) match(state request.Request) *rule {
+	if len(e.scenarios) == 0 {
+		return nil
+	}
+	now := time.Now()
+	qname, qtype := state.Name(), state.QType()
+	for _, s := range e.scenarios {
+		if !s.active(e.start, now) {
+			continue
+		}
+		for i := range s.rules {
+			if s.rules[i].match(qname, qtype) {
+				return &s.rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// scenario returns the scenario with name, or nil if there is none.
+func (e *Erratic) scenario(name string) *scenario {
+	for _, s := range e.scenarios {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
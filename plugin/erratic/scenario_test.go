@@ -0,0 +1,110 @@
+package erratic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// countWriter counts the replies written.
+type countWriter struct {
+	test.ResponseWriter
+	msgs []*dns.Msg
+}
+
+func (w *countWriter) WriteMsg(m *dns.Msg) error {
+	w.msgs = append(w.msgs, m.Copy())
+	return nil
+}
+
+func TestScenario(t *testing.T) {
+	e := &Erratic{start: time.Now(), scenarios: []*scenario{
+		{name: "a", rules: []rule{
+			{name: "fail.example.org.", qtype: dns.TypeA, action: actRcode, rcode: dns.RcodeRefused},
+			{name: "drop.example.org.", qtype: dns.TypeANY, action: actDrop},
+			{name: "id.example.org.", qtype: dns.TypeAAAA, action: actCorruptID},
+			{name: "dup.example.org.", qtype: dns.TypeANY, action: actDuplicate, count: 2},
+			{name: "tc.example.org.", qtype: dns.TypeA, action: actTruncate},
+		}},
+	}}
+
+	tests := []struct {
+		qname     string
+		qtype     uint16
+		replies   int
+		rcode     int
+		corruptID bool
+		truncated bool
+	}{
+		{"fail.example.org.", dns.TypeA, 1, dns.RcodeRefused, false, false},
+		{"www.fail.example.org.", dns.TypeA, 1, dns.RcodeRefused, false, false},
+		{"fail.example.org.", dns.TypeAAAA, 1, dns.RcodeSuccess, false, false},
+		{"drop.example.org.", dns.TypeMX, 0, 0, false, false},
+		{"id.example.org.", dns.TypeAAAA, 1, dns.RcodeSuccess, true, false},
+		{"dup.example.org.", dns.TypeTXT, 3, dns.RcodeSuccess, false, false},
+		{"tc.example.org.", dns.TypeA, 1, dns.RcodeSuccess, false, true},
+		{"example.org.", dns.TypeA, 1, dns.RcodeSuccess, false, false},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(tc.qname, tc.qtype)
+
+		w := &countWriter{}
+		e.ServeDNS(context.TODO(), w, req)
+
+		if len(w.msgs) != tc.replies {
+			t.Errorf("Test %d: expected %d replies, got %d", i, tc.replies, len(w.msgs))
+			continue
+		}
+		if tc.replies == 0 {
+			continue
+		}
+		m := w.msgs[0]
+		if m.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, m.Rcode)
+		}
+		if (m.Id != req.Id) != tc.corruptID {
+			t.Errorf("Test %d: expected corrupt ID to be %t, got ID %d for %d", i, tc.corruptID, m.Id, req.Id)
+		}
+		if m.Truncated != tc.truncated {
+			t.Errorf("Test %d: expected truncated to be %t", i, tc.truncated)
+		}
+	}
+}
+
+func TestScenarioActive(t *testing.T) {
+	start := time.Now()
+	s := &scenario{period: time.Minute, duration: 10 * time.Second}
+
+	if !s.active(start, start.Add(65*time.Second)) {
+		t.Error("Expected scenario to be active inside its window")
+	}
+	if s.active(start, start.Add(30*time.Second)) {
+		t.Error("Expected scenario to be inactive outside its window")
+	}
+
+	s.enable(false)
+	if s.active(start, start.Add(65*time.Second)) {
+		t.Error("Expected disabled scenario to be inactive")
+	}
+}
+
+func TestScenarioDisabled(t *testing.T) {
+	s := &scenario{name: "a", rules: []rule{{name: ".", qtype: dns.TypeANY, action: actDrop}}}
+	s.enable(false)
+	e := &Erratic{start: time.Now(), scenarios: []*scenario{s}}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	e.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg == nil {
+		t.Fatal("Expected a reply when the scenario is disabled")
+	}
+}
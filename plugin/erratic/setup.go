@@ -7,10 +7,15 @@ import (
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/adminapi"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
+var log = clog.NewWithPlugin("erratic")
+
 func init() {
 	caddy.RegisterPlugin("erratic", caddy.Plugin{
 		ServerType: "dns",
@@ -28,11 +33,16 @@ func setup(c *caddy.Controller) error {
 		return e
 	})
 
+	if e.admin != "" {
+		a := adminapi.Register(c, uniqAdmin, e.admin, newAdmin()).(*admin)
+		a.add(e)
+	}
+
 	return nil
 }
 
 func parseErratic(c *caddy.Controller) (*Erratic, error) {
-	e := &Erratic{drop: 2}
+	e := &Erratic{drop: 2, start: time.Now()}
 	drop := false // true if we've seen the drop keyword
 
 	for c.Next() { // 'erratic'
@@ -106,14 +116,131 @@ func parseErratic(c *caddy.Controller) (*Erratic, error) {
 				e.truncate = uint64(amount)
 			case "large":
 				e.large = true
+			case "scenario":
+				args := c.RemainingArgs()
+				if len(args) < 4 {
+					return nil, c.ArgErr()
+				}
+				r, err := parseRule(args[1:])
+				if err != nil {
+					return nil, err
+				}
+				s := e.scenario(args[0])
+				if s == nil {
+					s = &scenario{name: args[0]}
+					e.scenarios = append(e.scenarios, s)
+				}
+				s.rules = append(s.rules, r)
+			case "window":
+				args := c.RemainingArgs()
+				if len(args) != 3 {
+					return nil, c.ArgErr()
+				}
+				s := e.scenario(args[0])
+				if s == nil {
+					return nil, fmt.Errorf("unknown scenario %q", args[0])
+				}
+				period, err := time.ParseDuration(args[1])
+				if err != nil {
+					return nil, err
+				}
+				duration, err := time.ParseDuration(args[2])
+				if err != nil {
+					return nil, err
+				}
+				if period <= 0 || duration <= 0 || duration > period {
+					return nil, fmt.Errorf("invalid window %s %s", args[1], args[2])
+				}
+				s.period, s.duration = period, duration
+			case "disable":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, name := range args {
+					s := e.scenario(name)
+					if s == nil {
+						return nil, fmt.Errorf("unknown scenario %q", name)
+					}
+					s.enable(false)
+				}
+			case "admin":
+				args := c.RemainingArgs()
+				switch len(args) {
+				case 0:
+					e.admin = defAdminAddr
+				case 1:
+					e.admin = args[0]
+				default:
+					return nil, c.ArgErr()
+				}
 			default:
 				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
 	}
-	if (e.delay > 0 || e.truncate > 0) && !drop { // delay is set, but we've haven't seen a drop keyword, remove default drop stuff
+	if (e.delay > 0 || e.truncate > 0 || len(e.scenarios) > 0) && !drop { // delay is set, but we've haven't seen a drop keyword, remove default drop stuff
 		e.drop = 0
 	}
 
 	return e, nil
 }
+
+// parseRule parses NAME TYPE ACTION [ARG].
+func parseRule(args []string) (rule, error) {
+	r := rule{name: plugin.Name(args[0]).Normalize()}
+
+	qtype, ok := dns.StringToType[args[1]]
+	if !ok {
+		return r, fmt.Errorf("invalid RR type %s", args[1])
+	}
+	r.qtype = qtype
+
+	act, ok := actions[args[2]]
+	if !ok {
+		return r, fmt.Errorf("unknown action %q", args[2])
+	}
+	r.action = act
+
+	args = args[3:]
+	switch act {
+	case actRcode:
+		if len(args) != 1 {
+			return r, fmt.Errorf("action rcode needs an rcode")
+		}
+		rcode, ok := dns.StringToRcode[args[0]]
+		if !ok {
+			return r, fmt.Errorf("unknown rcode %s", args[0])
+		}
+		r.rcode = rcode
+	case actDelay:
+		if len(args) != 1 {
+			return r, fmt.Errorf("action delay needs a duration")
+		}
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return r, err
+		}
+		r.delay = d
+	case actDuplicate:
+		r.count = 1
+		if len(args) > 1 {
+			return r, fmt.Errorf("too many arguments for action duplicate")
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return r, err
+			}
+			if n < 1 {
+				return r, fmt.Errorf("illegal count value given %q", args[0])
+			}
+			r.count = n
+		}
+	default:
+		if len(args) > 0 {
+			return r, fmt.Errorf("too many arguments for action %s", r.action)
+		}
+	}
+	return r, nil
+}
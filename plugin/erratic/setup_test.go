@@ -101,3 +101,87 @@ func TestParseErratic(t *testing.T) {
 		}
 	}
 }
+
+func TestParseErraticScenario(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		scenarios int
+		rules     int // rules in the first scenario
+		drop      uint64
+	}{
+		// oks
+		{`erratic {
+			scenario outage example.org A rcode SERVFAIL
+			scenario outage example.org AAAA drop
+			scenario dup example.net ANY duplicate 2
+		}`, false, 2, 2, 0},
+		{`erratic {
+			drop 3
+			scenario outage example.org A corrupt_id
+			window outage 5m 30s
+			disable outage
+			admin localhost:0
+		}`, false, 1, 1, 3},
+		// fails
+		{`erratic {
+			scenario outage example.org A
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org FOO drop
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A explode
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A rcode FOO
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A delay
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A duplicate 0
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A drop 1
+		}`, true, 0, 0, 0},
+		{`erratic {
+			window outage 5m 30s
+		}`, true, 0, 0, 0},
+		{`erratic {
+			scenario outage example.org A drop
+			window outage 30s 5m
+		}`, true, 0, 0, 0},
+		{`erratic {
+			disable outage
+		}`, true, 0, 0, 0},
+		{`erratic {
+			admin a b
+		}`, true, 0, 0, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		e, err := parseErratic(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+
+		if test.shouldErr {
+			continue
+		}
+
+		if len(e.scenarios) != test.scenarios {
+			t.Fatalf("Test %v: Expected %d scenarios but found: %d", i, test.scenarios, len(e.scenarios))
+		}
+		if len(e.scenarios[0].rules) != test.rules {
+			t.Errorf("Test %v: Expected %d rules but found: %d", i, test.rules, len(e.scenarios[0].rules))
+		}
+		if test.drop != e.drop {
+			t.Errorf("Test %v: Expected drop %d but found: %d", i, test.drop, e.drop)
+		}
+	}
+}
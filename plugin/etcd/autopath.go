@@ -0,0 +1,39 @@
+package etcd
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/autopath"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// AutoPath implements the AutoPathFunc call from the autopath plugin.
+// It looks up the PTR record of the client in etcd and returns the search path derived from
+// that name, or nil when the client has no PTR record.
+func (e *Etcd) AutoPath(state request.Request) []string {
+	zone := plugin.Zones(e.Zones).Matches(state.Name())
+	if zone == "" {
+		return nil
+	}
+
+	reverse, err := dns.ReverseAddr(state.IP())
+	if err != nil {
+		return nil
+	}
+	req := new(dns.Msg)
+	req.SetQuestion(reverse, dns.TypePTR)
+
+	services, err := e.Records(context.Background(), request.Request{W: state.W, Req: req}, true)
+	if err != nil {
+		return nil
+	}
+	for _, serv := range services {
+		if search := autopath.SearchPath(serv.Host, zone); search != nil {
+			return search
+		}
+	}
+	return nil
+}
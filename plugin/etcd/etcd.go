@@ -37,6 +37,10 @@ type Etcd struct {
 	Upstream   *upstream.Upstream
 	Client     *etcdcv3.Client
 
+	lease     bool     // only services attached to a lease are healthy
+	store     *store   // when not nil, queries are answered from this copy of etcd
+	weighted  int      // when > 0, the number of services picked by weight for A and AAAA answers
+	regions   []region // client subnet to region mapping, most specific first
 	endpoints []string // Stored here as well, to aid in testing.
 }
 
@@ -48,6 +52,19 @@ func (e *Etcd) Services(ctx context.Context, state request.Request, exact bool,
 	}
 
 	services = msg.Group(services)
+
+	switch state.QType() {
+	case dns.TypeA, dns.TypeAAAA:
+		services = msg.Healthy(services)
+		if region := e.clientRegion(state); region != "" {
+			services = msg.Region(services, region)
+		}
+		if e.weighted > 0 {
+			services = msg.Weighted(services, e.weighted)
+		}
+	case dns.TypeSRV:
+		services = msg.Healthy(services)
+	}
 	return
 }
 
@@ -72,12 +89,22 @@ func (e *Etcd) Records(ctx context.Context, state request.Request, exact bool) (
 	name := state.Name()
 
 	path, star := msg.PathWithWildcard(name, e.PathPrefix)
-	r, err := e.get(ctx, path, !exact)
-	if err != nil {
-		return nil, err
+
+	var kvs []*mvccpb.KeyValue
+	if e.store != nil && e.store.synced() {
+		var err error
+		if kvs, err = e.store.get(path, !exact); err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := e.get(ctx, path, !exact)
+		if err != nil {
+			return nil, err
+		}
+		kvs = r.Kvs
 	}
 	segments := strings.Split(msg.Path(name, e.PathPrefix), "/")
-	return e.loopNodes(r.Kvs, segments, star, state.QType())
+	return e.loopNodes(kvs, segments, star, state.QType())
 }
 
 func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.GetResponse, error) {
@@ -139,10 +166,17 @@ Nodes:
 			return nil, fmt.Errorf("%s: %s", n.Key, err.Error())
 		}
 		serv.Key = string(n.Key)
-		if _, ok := bx[*serv]; ok {
+		if e.lease && n.Lease == 0 {
+			// Not attached to a lease, so nobody vouches for this service being alive.
+			serv.Healthy = new(bool)
+		}
+		// Healthy is a pointer, leave it out when checking for duplicates.
+		dup := *serv
+		dup.Healthy = nil
+		if _, ok := bx[dup]; ok {
 			continue
 		}
-		bx[*serv] = struct{}{}
+		bx[dup] = struct{}{}
 
 		serv.TTL = e.TTL(n, serv)
 		if serv.Priority == 0 {
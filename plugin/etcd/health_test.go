@@ -0,0 +1,111 @@
+// +build etcd
+
+package etcd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	etcdcv3 "github.com/coreos/etcd/clientv3"
+	"github.com/miekg/dns"
+)
+
+func TestHealthLookup(t *testing.T) {
+	etc := newEtcdPlugin()
+
+	for _, serv := range servicesHealth {
+		set(t, etc, serv.Key, 0, serv)
+		defer delete(t, etc, serv.Key)
+	}
+	for _, tc := range dnsTestCasesHealth {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := etc.ServeDNS(ctxt, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+
+		resp := rec.Msg
+		if err := test.SortAndCheck(resp, tc); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestHealthLease(t *testing.T) {
+	etc := newEtcdPlugin()
+	etc.lease = true
+
+	lease, err := etc.Client.Grant(ctxt, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer etc.Client.Revoke(ctxt, lease.ID)
+
+	set(t, etc, "a.lease.skydns.test.", 0, &msg.Service{Host: "10.0.0.1"})
+	defer delete(t, etc, "a.lease.skydns.test.")
+
+	b, _ := json.Marshal(&msg.Service{Host: "10.0.0.2"})
+	path, _ := msg.PathWithWildcard("b.lease.skydns.test.", etc.PathPrefix)
+	if _, err := etc.Client.KV.Put(ctxt, path, string(b), etcdcv3.WithLease(lease.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("lease.skydns.test.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := etc.ServeDNS(ctxt, rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != "10.0.0.2" {
+		t.Errorf("Expected only the service with a lease, got %v", rec.Msg.Answer)
+	}
+}
+
+var (
+	healthy   = true
+	unhealthy = false
+)
+
+// Note the key is encoded as DNS name, while in "reality" it is a etcd path.
+var servicesHealth = []*msg.Service{
+	{Host: "127.0.0.1", Key: "a.health.skydns.test."},
+	{Host: "127.0.0.2", Key: "b.health.skydns.test.", Healthy: &unhealthy},
+	{Host: "127.0.0.3", Key: "c.health.skydns.test.", Healthy: &healthy},
+
+	{Host: "127.0.0.1", Port: 80, Key: "a.health-srv.skydns.test.", Healthy: &unhealthy},
+	{Host: "127.0.0.2", Port: 80, Key: "b.health-srv.skydns.test."},
+
+	{Host: "127.0.0.1", Key: "a.down.skydns.test.", Healthy: &unhealthy},
+	{Host: "127.0.0.2", Key: "b.down.skydns.test.", Healthy: &unhealthy},
+}
+
+var dnsTestCasesHealth = []test.Case{
+	{
+		// unhealthy service is left out
+		Qname: "health.skydns.test.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("health.skydns.test. 300 IN A 127.0.0.1"),
+			test.A("health.skydns.test. 300 IN A 127.0.0.3"),
+		},
+	},
+	{
+		Qname: "health-srv.skydns.test.", Qtype: dns.TypeSRV,
+		Answer: []dns.RR{test.SRV("health-srv.skydns.test. 300 IN SRV 10 100 80 b.health-srv.skydns.test.")},
+		Extra:  []dns.RR{test.A("b.health-srv.skydns.test. 300 IN A 127.0.0.2")},
+	},
+	{
+		// all unhealthy, return everything
+		Qname: "down.skydns.test.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("down.skydns.test. 300 IN A 127.0.0.1"),
+			test.A("down.skydns.test. 300 IN A 127.0.0.2"),
+		},
+	},
+}
@@ -2,6 +2,7 @@
 package msg
 
 import (
+	"math/rand"
 	"net"
 	"strings"
 
@@ -32,6 +33,15 @@ type Service struct {
 	// answer.
 	Group string `json:"group,omitempty"`
 
+	// Healthy, when set to false, marks the service as unhealthy. Unhealthy services are left
+	// out of A, AAAA and SRV answers, unless all services are unhealthy. When not set the
+	// service is considered healthy.
+	Healthy *bool `json:"healthy,omitempty"`
+
+	// Region is the region the service is in. When the client's region is known, services in
+	// other regions are left out of A and AAAA answers.
+	Region string `json:"region,omitempty"`
+
 	// Etcd key where we found this service and ignored from json un-/marshalling
 	Key string `json:"-"`
 }
@@ -141,6 +151,85 @@ func Group(sx []Service) []Service {
 	return ret
 }
 
+// IsHealthy returns true if the service isn't marked as unhealthy.
+func (s *Service) IsHealthy() bool {
+	return s.Healthy == nil || *s.Healthy
+}
+
+// Healthy returns the healthy services in sx. If none of the services are healthy all
+// of them are returned, as it is better to return something than nothing at all.
+func Healthy(sx []Service) []Service {
+	healthy := 0
+	for i := range sx {
+		if sx[i].IsHealthy() {
+			healthy++
+		}
+	}
+	if healthy == 0 || healthy == len(sx) {
+		return sx
+	}
+
+	ret := make([]Service, 0, healthy)
+	for _, s := range sx {
+		if s.IsHealthy() {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// Region returns the services in sx that are in region or have no region set. If there are no
+// such services all of sx is returned.
+func Region(sx []Service, region string) []Service {
+	ret := []Service{}
+	for _, s := range sx {
+		if s.Region == "" || s.Region == region {
+			ret = append(ret, s)
+		}
+	}
+	if len(ret) == 0 {
+		return sx
+	}
+	return ret
+}
+
+// Weighted returns n services from sx, picked at random where the chance of a service being
+// picked is proportional to its Weight. Services without a Weight have a weight of 100, like
+// they do in SRV records. If sx holds n or fewer services, sx is returned.
+func Weighted(sx []Service, n int) []Service {
+	if len(sx) <= n {
+		return sx
+	}
+
+	weights := make([]int, len(sx))
+	total := 0
+	for i, s := range sx {
+		weights[i] = s.Weight
+		if weights[i] <= 0 {
+			weights[i] = 100
+		}
+		total += weights[i]
+	}
+
+	ret := make([]Service, 0, n)
+	for len(ret) < n {
+		r := rand.Intn(total)
+		for i, w := range weights {
+			if w == 0 {
+				continue // already picked
+			}
+			if r < w {
+				ret = append(ret, sx[i])
+				total -= w
+				weights[i] = 0
+				break
+			}
+			r -= w
+		}
+	}
+	return ret
+}
+
 // Split255 splits a string into 255 byte chunks.
 func split255(s string) []string {
 	if len(s) < 255 {
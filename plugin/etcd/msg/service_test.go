@@ -124,6 +124,82 @@ func TestGroup(t *testing.T) {
 	}
 }
 
+func TestHealthy(t *testing.T) {
+	healthy, unhealthy := true, false
+
+	sx := Healthy(
+		[]Service{
+			{Host: "127.0.0.1", Key: "a/dom/skydns/test"},
+			{Host: "127.0.0.2", Key: "b/dom/skydns/test", Healthy: &unhealthy},
+			{Host: "127.0.0.3", Key: "c/dom/skydns/test", Healthy: &healthy},
+		},
+	)
+	if len(sx) != 2 || sx[0].Host != "127.0.0.1" || sx[1].Host != "127.0.0.3" {
+		t.Fatalf("Failure to filter unhealthy services: %v", sx)
+	}
+
+	// All unhealthy, return all of them.
+	sx = Healthy(
+		[]Service{
+			{Host: "127.0.0.1", Key: "a/dom/skydns/test", Healthy: &unhealthy},
+			{Host: "127.0.0.2", Key: "b/dom/skydns/test", Healthy: &unhealthy},
+		},
+	)
+	if len(sx) != 2 {
+		t.Fatalf("Failure to return all unhealthy services: %v", sx)
+	}
+}
+
+func TestRegion(t *testing.T) {
+	sx := Region(
+		[]Service{
+			{Host: "127.0.0.1", Region: "eu", Key: "a/dom/skydns/test"},
+			{Host: "127.0.0.2", Region: "us", Key: "b/dom/skydns/test"},
+			{Host: "127.0.0.3", Key: "c/dom/skydns/test"},
+		}, "eu",
+	)
+	if len(sx) != 2 || sx[0].Host != "127.0.0.1" || sx[1].Host != "127.0.0.3" {
+		t.Fatalf("Failure to filter services by region: %v", sx)
+	}
+
+	// Nothing in the region, return all of them.
+	sx = Region(
+		[]Service{
+			{Host: "127.0.0.1", Region: "eu", Key: "a/dom/skydns/test"},
+			{Host: "127.0.0.2", Region: "us", Key: "b/dom/skydns/test"},
+		}, "asia",
+	)
+	if len(sx) != 2 {
+		t.Fatalf("Failure to return all services when none are in the region: %v", sx)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	sx := []Service{
+		{Host: "127.0.0.1", Weight: 1, Key: "a/dom/skydns/test"},
+		{Host: "127.0.0.2", Weight: 1000000, Key: "b/dom/skydns/test"},
+		{Host: "127.0.0.3", Weight: 1, Key: "c/dom/skydns/test"},
+	}
+
+	if w := Weighted(sx, 3); len(w) != 3 {
+		t.Fatalf("Expected all services, got %v", w)
+	}
+
+	heavy := 0
+	for i := 0; i < 100; i++ {
+		w := Weighted(sx, 2)
+		if len(w) != 2 || w[0].Host == w[1].Host {
+			t.Fatalf("Expected 2 different services, got %v", w)
+		}
+		if w[0].Host == "127.0.0.2" || w[1].Host == "127.0.0.2" {
+			heavy++
+		}
+	}
+	if heavy < 95 {
+		t.Errorf("Expected the heavy service to be picked almost always, got %d out of %d", heavy, 100)
+	}
+}
+
 func BenchmarkNewSRV(b *testing.B) {
 	s := &Service{Host: "www,example.org", Port: 8080}
 	for n := 0; n < b.N; n++ {
@@ -0,0 +1,44 @@
+package etcd
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// region maps client subnets to a region name.
+type region struct {
+	net  *net.IPNet
+	name string
+}
+
+// clientRegion returns the region of the client, or the empty string when it is unknown. The client
+// address is taken from the EDNS0 client subnet option, or the source address of the query.
+func (e *Etcd) clientRegion(state request.Request) string {
+	if len(e.regions) == 0 {
+		return ""
+	}
+
+	ip := net.ParseIP(state.IP())
+	if o := state.Req.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if ecs, ok := opt.(*dns.EDNS0_SUBNET); ok {
+				ip = ecs.Address
+				break
+			}
+		}
+	}
+	if ip == nil {
+		return ""
+	}
+
+	// Regions are sorted, most specific subnet first.
+	for _, r := range e.regions {
+		if r.net.Contains(ip) {
+			return r.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package etcd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestClientRegion(t *testing.T) {
+	_, eu, _ := net.ParseCIDR("10.0.0.0/8")
+	_, euWest, _ := net.ParseCIDR("10.240.0.0/16")
+	_, us, _ := net.ParseCIDR("192.168.0.0/16")
+	e := &Etcd{regions: []region{{euWest, "eu-west"}, {eu, "eu"}, {us, "us"}}}
+
+	tests := []struct {
+		ecs      string // client subnet in the query, if any
+		expected string
+	}{
+		{"", "eu-west"}, // test.ResponseWriter's address is 10.240.0.1
+		{"10.1.0.0", "eu"},
+		{"192.168.1.0", "us"},
+		{"172.16.0.0", ""},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.ecs != "" {
+			m.SetEdns0(4096, false)
+			o := m.IsEdns0()
+			o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(tc.ecs)})
+		}
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+
+		if r := e.clientRegion(state); r != tc.expected {
+			t.Errorf("Test %d: expected region %q, got %q", i, tc.expected, r)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package etcd
 
 import (
+	"context"
 	"crypto/tls"
+	"net"
+	"sort"
+	"strconv"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -28,6 +32,18 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("etcd", err)
 	}
 
+	if e.store != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.OnStartup(func() error {
+			go e.sync(ctx)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			cancel()
+			return nil
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		e.Next = next
 		return e
@@ -84,6 +100,43 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 				if err != nil {
 					return &Etcd{}, err
 				}
+			case "lease":
+				if c.NextArg() {
+					return &Etcd{}, c.ArgErr()
+				}
+				etc.lease = true
+			case "sync":
+				if c.NextArg() {
+					return &Etcd{}, c.ArgErr()
+				}
+				etc.store = newStore()
+			case "weighted":
+				etc.weighted = 1
+				if c.NextArg() {
+					n, err := strconv.Atoi(c.Val())
+					if err != nil {
+						return &Etcd{}, err
+					}
+					if n <= 0 {
+						return &Etcd{}, c.Errf("weighted count must be positive: %d", n)
+					}
+					etc.weighted = n
+				}
+				if c.NextArg() {
+					return &Etcd{}, c.ArgErr()
+				}
+			case "region":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return &Etcd{}, c.ArgErr()
+				}
+				for _, cidr := range args[1:] {
+					_, n, err := net.ParseCIDR(cidr)
+					if err != nil {
+						return &Etcd{}, c.Errf("invalid region subnet %q: %s", cidr, err)
+					}
+					etc.regions = append(etc.regions, region{net: n, name: args[0]})
+				}
 			case "credentials":
 				args := c.RemainingArgs()
 				if len(args) == 0 {
@@ -99,6 +152,12 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 				}
 			}
 		}
+		sort.SliceStable(etc.regions, func(i, j int) bool {
+			oi, _ := etc.regions[i].net.Mask.Size()
+			oj, _ := etc.regions[j].net.Mask.Size()
+			return oi > oj
+		})
+
 		client, err := newEtcdClient(endpoints, tlsConfig, username, password)
 		if err != nil {
 			return &Etcd{}, err
@@ -64,6 +64,50 @@ func TestSetupEtcd(t *testing.T) {
 		}
 			`, false, "skydns", []string{"http://localhost:2379"}, "", "username", "password",
 		},
+		// with lease based liveness
+		{
+			`etcd {
+			endpoint http://localhost:2379
+			lease
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd {
+			lease yes
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "Wrong argument count", "", "",
+		},
+		// with a synced copy of etcd
+		{
+			`etcd {
+			endpoint http://localhost:2379
+			sync
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		// with weighted and regional answers
+		{
+			`etcd {
+			endpoint http://localhost:2379
+			weighted 2
+			region eu 10.0.0.0/8 172.16.0.0/12
+			region us 192.168.0.0/16
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd {
+			weighted 0
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "weighted count must be positive", "", "",
+		},
+		{
+			`etcd {
+			region eu 10.0.0.0
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "invalid region subnet", "", "",
+		},
 		// with credentials, missing password
 		{
 			`etcd {
@@ -0,0 +1,193 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	etcdcv3 "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// store is an in-memory copy of the keys under the path prefix, it is kept up to date with an etcd watch.
+type store struct {
+	sync.RWMutex
+	kvs      []*mvccpb.KeyValue // sorted by key
+	revision int64              // etcd revision of the data in the store, 0 when not synced yet
+	serial   uint32             // SOA serial, moved forward on every change
+}
+
+func newStore() *store { return &store{} }
+
+// synced returns true if the store has been loaded from etcd.
+func (s *store) synced() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.revision > 0
+}
+
+// Revision returns the etcd revision of the data in the store.
+func (s *store) Revision() int64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.revision
+}
+
+// Serial returns the SOA serial of the data in the store.
+func (s *store) Serial() uint32 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.serial
+}
+
+// bump moves the serial forward for a change: to the current time or, if that isn't later (two changes
+// within a second), by one. The caller must hold the lock.
+func (s *store) bump() {
+	if now := uint32(time.Now().Unix()); now > s.serial {
+		s.serial = now
+		return
+	}
+	s.serial++
+}
+
+// search returns the index of key in the store, or where it would be inserted. The caller must hold the lock.
+func (s *store) search(key string) int {
+	return sort.Search(len(s.kvs), func(i int) bool { return string(s.kvs[i].Key) >= key })
+}
+
+// get looks up path in the store, it returns the same values as Etcd.get would from etcd.
+func (s *store) get(path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if recursive {
+		prefix := path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		var kvs []*mvccpb.KeyValue
+		for i := s.search(prefix); i < len(s.kvs) && strings.HasPrefix(string(s.kvs[i].Key), prefix); i++ {
+			kvs = append(kvs, s.kvs[i])
+		}
+		if len(kvs) > 0 {
+			return kvs, nil
+		}
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	i := s.search(path)
+	if i == len(s.kvs) || string(s.kvs[i].Key) != path {
+		return nil, errKeyNotFound
+	}
+	return []*mvccpb.KeyValue{s.kvs[i]}, nil
+}
+
+// reset replaces the contents of the store with kvs at revision.
+func (s *store) reset(kvs []*mvccpb.KeyValue, revision int64) {
+	sorted := make([]*mvccpb.KeyValue, len(kvs))
+	copy(sorted, kvs)
+	sort.Slice(sorted, func(i, j int) bool { return string(sorted[i].Key) < string(sorted[j].Key) })
+
+	s.Lock()
+	// A reload after the watch broke often finds the same data, that doesn't need a new serial.
+	if s.revision == 0 || !sameKeyValues(s.kvs, sorted) {
+		s.bump()
+	}
+	s.kvs, s.revision = sorted, revision
+	s.Unlock()
+}
+
+// sameKeyValues returns true if a and b, both sorted by key, hold the same keys at the same revisions.
+func sameKeyValues(a, b []*mvccpb.KeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if string(a[i].Key) != string(b[i].Key) || a[i].ModRevision != b[i].ModRevision {
+			return false
+		}
+	}
+	return true
+}
+
+// apply applies the watch events to the store and moves it to revision.
+func (s *store) apply(events []*etcdcv3.Event, revision int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		i := s.search(key)
+		exists := i < len(s.kvs) && string(s.kvs[i].Key) == key
+
+		switch ev.Type {
+		case mvccpb.PUT:
+			if exists {
+				s.kvs[i] = ev.Kv
+				continue
+			}
+			s.kvs = append(s.kvs, nil)
+			copy(s.kvs[i+1:], s.kvs[i:])
+			s.kvs[i] = ev.Kv
+		case mvccpb.DELETE:
+			if exists {
+				s.kvs = append(s.kvs[:i], s.kvs[i+1:]...)
+			}
+		}
+	}
+	s.revision = revision
+	if len(events) > 0 {
+		s.bump()
+	}
+}
+
+// sync loads everything under the path prefix into the store and keeps it up to date until ctx is
+// canceled. When loading fails or the watch breaks, the store keeps serving its current contents.
+func (e *Etcd) sync(ctx context.Context) {
+	prefix := "/" + e.PathPrefix + "/"
+	for {
+		if err := e.load(ctx, prefix); err != nil {
+			log.Warningf("Failed to load %s from etcd: %s", prefix, err)
+		} else {
+			e.watch(ctx, prefix)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(syncRetry):
+		}
+	}
+}
+
+// load does a full read of the keys under prefix.
+func (e *Etcd) load(ctx context.Context, prefix string) error {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, prefix, etcdcv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	e.store.reset(r.Kvs, r.Header.Revision)
+	return nil
+}
+
+// watch applies the changes under prefix to the store, it returns when the watch fails or ctx is canceled.
+func (e *Etcd) watch(ctx context.Context, prefix string) {
+	ctx, cancel := context.WithCancel(etcdcv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	wch := e.Client.Watch(ctx, prefix, etcdcv3.WithPrefix(), etcdcv3.WithRev(e.store.Revision()+1))
+	for resp := range wch {
+		if err := resp.Err(); err != nil {
+			// Also returned when our revision has been compacted, a full reload fixes this.
+			log.Warningf("Watch of %s failed: %s", prefix, err)
+			return
+		}
+		e.store.apply(resp.Events, resp.Header.Revision)
+	}
+}
+
+const syncRetry = 5 * time.Second
@@ -0,0 +1,106 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	etcdcv3 "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+func kv(key string) *mvccpb.KeyValue { return &mvccpb.KeyValue{Key: []byte(key)} }
+
+func TestStore(t *testing.T) {
+	start := uint32(time.Now().Unix())
+	s := newStore()
+	if s.synced() {
+		t.Fatal("Expected store not to be synced")
+	}
+	s.reset([]*mvccpb.KeyValue{
+		kv("/skydns/test/skydns/mx/b"),
+		kv("/skydns/test/skydns/mx/a"),
+		kv("/skydns/test/skydns/mx1"),
+	}, 10)
+
+	s.apply([]*etcdcv3.Event{
+		{Type: mvccpb.PUT, Kv: kv("/skydns/test/skydns/mx/c")},
+		{Type: mvccpb.PUT, Kv: kv("/skydns/test/skydns/a")},
+		{Type: mvccpb.DELETE, Kv: kv("/skydns/test/skydns/mx/b")},
+		{Type: mvccpb.DELETE, Kv: kv("/skydns/test/skydns/nosuch")},
+	}, 12)
+
+	if r := s.Revision(); r != 12 {
+		t.Errorf("Expected revision %d, got %d", 12, r)
+	}
+	if serial := s.Serial(); serial < start {
+		t.Errorf("Expected serial of at least %d, got %d", start, serial)
+	}
+
+	tests := []struct {
+		path      string
+		recursive bool
+		expected  []string
+	}{
+		{"/skydns/test/skydns/mx", true, []string{"/skydns/test/skydns/mx/a", "/skydns/test/skydns/mx/c"}},
+		{"/skydns/test/skydns/mx1", true, []string{"/skydns/test/skydns/mx1"}},
+		{"/skydns/test/skydns/mx1", false, []string{"/skydns/test/skydns/mx1"}},
+		{"/skydns/test/skydns/mx", false, nil},
+		{"/skydns/test/skydns/mx/b", true, nil},
+		{"/skydns/test/skydns/a", true, []string{"/skydns/test/skydns/a"}},
+	}
+
+	for i, tc := range tests {
+		kvs, err := s.get(tc.path, tc.recursive)
+		if tc.expected == nil {
+			if err != errKeyNotFound {
+				t.Errorf("Test %d: expected %s, got %v", i, errKeyNotFound, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(kvs) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d keys, got %d", i, len(tc.expected), len(kvs))
+			continue
+		}
+		for j, kv := range kvs {
+			if string(kv.Key) != tc.expected[j] {
+				t.Errorf("Test %d: expected key %s, got %s", i, tc.expected[j], kv.Key)
+			}
+		}
+	}
+}
+
+func TestStoreSerial(t *testing.T) {
+	s := newStore()
+	kvs := []*mvccpb.KeyValue{{Key: []byte("/skydns/test/skydns/a"), ModRevision: 10}}
+	s.reset(kvs, 10)
+	serial := s.Serial()
+	if serial == 0 {
+		t.Fatal("Expected a serial after the first load")
+	}
+
+	// Changes within the same second still get a new serial.
+	for i := 0; i < 3; i++ {
+		s.apply([]*etcdcv3.Event{{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/skydns/test/skydns/b"), ModRevision: int64(11 + i)}}}, int64(11+i))
+		if x := s.Serial(); x <= serial {
+			t.Errorf("Expected serial to go up from %d, got %d", serial, x)
+		}
+		serial = s.Serial()
+	}
+
+	// A reload that finds the same data keeps the serial.
+	kvs = append(kvs, &mvccpb.KeyValue{Key: []byte("/skydns/test/skydns/b"), ModRevision: 13})
+	s.reset(kvs, 20)
+	if x := s.Serial(); x != serial {
+		t.Errorf("Expected serial %d after reload of the same data, got %d", serial, x)
+	}
+
+	kvs[1] = &mvccpb.KeyValue{Key: []byte("/skydns/test/skydns/b"), ModRevision: 21}
+	s.reset(kvs, 21)
+	if x := s.Serial(); x <= serial {
+		t.Errorf("Expected serial to go up from %d after reload of changed data, got %d", serial, x)
+	}
+}
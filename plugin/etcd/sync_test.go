@@ -0,0 +1,72 @@
+//go:build etcd
+// +build etcd
+
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestSyncLookup(t *testing.T) {
+	etc := newEtcdPlugin()
+	etc.store = newStore()
+
+	set(t, etc, "a.sync.skydns.test.", 0, &msg.Service{Host: "10.0.0.1"})
+	defer delete(t, etc, "a.sync.skydns.test.")
+
+	m := new(dns.Msg)
+	m.SetQuestion("sync.skydns.test.", dns.TypeA)
+	before := etc.Serial(request.Request{W: &test.ResponseWriter{}, Req: m})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go etc.sync(ctx)
+
+	waitFor(t, func() bool { return etc.store.synced() })
+
+	set(t, etc, "b.sync.skydns.test.", 0, &msg.Service{Host: "10.0.0.2"})
+	defer delete(t, etc, "b.sync.skydns.test.")
+
+	waitFor(t, func() bool { _, err := etc.store.get("/skydns/test/skydns/sync/b", false); return err == nil })
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := etc.ServeDNS(ctxt, rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rec.Msg.Answer) != 2 {
+		t.Errorf("Expected 2 answers, got %d", len(rec.Msg.Answer))
+	}
+
+	serial := etc.Serial(request.Request{W: &test.ResponseWriter{}, Req: m})
+	if serial != etc.store.Serial() {
+		t.Errorf("Expected serial %d, got %d", etc.store.Serial(), serial)
+	}
+	if serial < before {
+		t.Errorf("Expected serial to not go backwards after sync, got %d before and %d after", before, serial)
+	}
+
+	delete(t, etc, "a.sync.skydns.test.")
+	waitFor(t, func() bool {
+		_, err := etc.store.get("/skydns/test/skydns/sync/a", false)
+		return err == errKeyNotFound
+	})
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	for i := 0; i < 100; i++ {
+		if f() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Timeout waiting for the store to sync")
+}
@@ -10,7 +10,13 @@ import (
 )
 
 // Serial implements the Transferer interface.
+// When the data is synced from etcd the serial moves forward on every change, starting from the time
+// of the first change; otherwise it is the current time. The serial doesn't go backwards once the
+// store is synced, and two changes within a second get different serials.
 func (e *Etcd) Serial(state request.Request) uint32 {
+	if e.store != nil && e.store.synced() {
+		return e.store.Serial()
+	}
 	return uint32(time.Now().Unix())
 }
 
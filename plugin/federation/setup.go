@@ -6,12 +6,15 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/kubernetes"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/miekg/dns"
 
 	"github.com/mholt/caddy"
 )
 
+var log = clog.NewWithPlugin("federation")
+
 func init() {
 	caddy.RegisterPlugin("federation", caddy.Plugin{
 		ServerType: "dns",
@@ -25,6 +28,8 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("federation", err)
 	}
 
+	log.Warning("federation is deprecated and will be removed in a future release, use the alias plugin instead")
+
 	// Do this in OnStartup, so all plugin has been initialized.
 	c.OnStartup(func() error {
 		m := dnsserver.GetConfig(c).Handler("kubernetes")
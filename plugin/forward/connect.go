@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -94,8 +95,13 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 		conn.UDPSize = 512
 	}
 
+	req := p.cookie.request(state.Req)
+	if p.transport.tlsConfig != nil {
+		req = pad(req)
+	}
+
 	conn.SetWriteDeadline(time.Now().Add(maxTimeout))
-	if err := conn.WriteMsg(state.Req); err != nil {
+	if err := conn.WriteMsg(req); err != nil {
 		conn.Close() // not giving it back
 		if err == io.EOF && cached {
 			return nil, ErrCachedClosed
@@ -115,7 +121,15 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 			return ret, err
 		}
 		// drop out-of-order responses
-		if state.Req.Id == ret.Id {
+		if state.Req.Id != ret.Id {
+			continue
+		}
+		// drop responses that don't carry our cookie
+		result := p.cookie.response(ret)
+		if result != cookieNone {
+			CookieCount.WithLabelValues(result, p.addr).Add(1)
+		}
+		if result != cookieMismatch {
 			break
 		}
 	}
@@ -130,8 +144,24 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 	RequestCount.WithLabelValues(p.addr).Add(1)
 	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
 	RequestDuration.WithLabelValues(p.addr).Observe(time.Since(start).Seconds())
+	if ret.Rcode == dns.RcodeBadCookie {
+		CookieCount.WithLabelValues("badcookie", p.addr).Add(1)
+	}
 
 	return ret, nil
 }
 
 const cumulativeAvgWeight = 4
+
+// pad returns a copy of r that is padded following RFC 8467, so queries sent over TLS don't leak their
+// size. Queries without an OPT record are returned as is.
+func pad(r *dns.Msg) *dns.Msg {
+	if r.IsEdns0() == nil {
+		return r
+	}
+	m := *r
+	m.Extra = make([]dns.RR, len(r.Extra))
+	copy(m.Extra, r.Extra)
+	edns.Pad(&m, edns.QueryBlockSize, dns.MaxMsgSize)
+	return &m
+}
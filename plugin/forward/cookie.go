@@ -0,0 +1,102 @@
+package forward
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// cookie holds the DNS cookies (RFC 7873) used with an upstream. The client cookie is random and
+// unique per upstream, the server cookie is the last one the upstream handed out.
+type cookie struct {
+	client string // hex encoded, like dns.EDNS0_COOKIE
+
+	sync.RWMutex
+	server string
+}
+
+func newCookie() *cookie {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &cookie{client: hex.EncodeToString(b)}
+}
+
+// request returns a copy of r that carries our cookie instead of the one from the client. Requests
+// without an OPT record are returned as is.
+func (c *cookie) request(r *dns.Msg) *dns.Msg {
+	if r.IsEdns0() == nil {
+		return r
+	}
+
+	c.RLock()
+	value := c.client + c.server
+	c.RUnlock()
+
+	m := *r
+	m.Extra = make([]dns.RR, len(r.Extra))
+	copy(m.Extra, r.Extra)
+	setCookie(&m, value)
+	return &m
+}
+
+// response checks the cookie in ret and learns the server cookie from it. It returns cookieMismatch if
+// ret carries a cookie that isn't ours, such a response should be dropped. The cookie is removed from
+// ret as it is meant for us and not for the client.
+func (c *cookie) response(ret *dns.Msg) string {
+	o := ret.IsEdns0()
+	if o == nil {
+		return cookieNone
+	}
+	var value string
+	for _, e := range o.Option {
+		if e, ok := e.(*dns.EDNS0_COOKIE); ok {
+			value = e.Cookie
+			break
+		}
+	}
+	if value == "" {
+		// Upstream doesn't do cookies.
+		return cookieNone
+	}
+	if len(value) < len(c.client) || value[:len(c.client)] != c.client {
+		return cookieMismatch
+	}
+
+	c.Lock()
+	c.server = value[len(c.client):]
+	c.Unlock()
+
+	setCookie(ret, "")
+	return cookieValid
+}
+
+// setCookie replaces the OPT record in m with one that has the COOKIE option set to value, or has it
+// removed when value is empty.
+func setCookie(m *dns.Msg, value string) {
+	for i, rr := range m.Extra {
+		o, ok := rr.(*dns.OPT)
+		if !ok {
+			continue
+		}
+		opt := &dns.OPT{Hdr: o.Hdr}
+		for _, e := range o.Option {
+			if e.Option() != dns.EDNS0COOKIE {
+				opt.Option = append(opt.Option, e)
+			}
+		}
+		if value != "" {
+			opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: value})
+		}
+		m.Extra[i] = opt
+		return
+	}
+}
+
+// Results of checking the cookie in a response.
+const (
+	cookieNone     = "none"
+	cookieValid    = "valid"
+	cookieMismatch = "mismatch"
+)
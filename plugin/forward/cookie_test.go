@@ -0,0 +1,111 @@
+package forward
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func cookieOf(m *dns.Msg) string {
+	o := m.IsEdns0()
+	if o == nil {
+		return ""
+	}
+	for _, e := range o.Option {
+		if c, ok := e.(*dns.EDNS0_COOKIE); ok {
+			return c.Cookie
+		}
+	}
+	return ""
+}
+
+func TestCookie(t *testing.T) {
+	const serverCookie = "0100000000000000aabbccddeeff0011"
+	bad := 0
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.SetEdns0(4096, false)
+
+		c := cookieOf(r)
+		if len(c) < 16 {
+			t.Errorf("Expected client cookie, got %q", c)
+			return
+		}
+		if c[16:] != serverCookie {
+			// Like the RFC says; reply with BADCOOKIE and a new server cookie.
+			bad++
+			ret.Rcode = dns.RcodeBadCookie
+		} else {
+			ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		}
+		o := ret.IsEdns0()
+		o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: c[:16] + serverCookie})
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy(s.Addr, transport.DNS)
+	f := New()
+	f.SetProxy(p)
+	defer f.OnShutdown()
+
+	const clientCookie = "0102030405060708"
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	o := m.IsEdns0()
+	o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: clientCookie})
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected NOERROR with an answer, got %s", rec.Msg)
+	}
+	if bad != 1 {
+		t.Errorf("Expected 1 BADCOOKIE, got %d", bad)
+	}
+	if c := cookieOf(rec.Msg); c != "" {
+		t.Errorf("Expected upstream cookie to be removed, got %s", c)
+	}
+	if c := cookieOf(m); c != clientCookie {
+		t.Errorf("Expected request to be left alone, got cookie %s", c)
+	}
+}
+
+func TestCookieResponse(t *testing.T) {
+	c := newCookie()
+	c.server = "0100000000000000aabbccddeeff0011"
+
+	tests := []struct {
+		cookie   string
+		expected string
+	}{
+		{"", cookieNone},
+		{c.client + "0100000000000000ffffffffffffffff", cookieValid},
+		{"0000000000000000" + c.server, cookieMismatch},
+		{"00", cookieMismatch},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetEdns0(4096, false)
+		if tc.cookie != "" {
+			o := m.IsEdns0()
+			o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: tc.cookie})
+		}
+		if x := c.response(m); x != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, x)
+		}
+	}
+	if c.server != "0100000000000000ffffffffffffffff" {
+		t.Errorf("Expected server cookie to be learned, got %s", c.server)
+	}
+}
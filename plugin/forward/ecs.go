@@ -0,0 +1,71 @@
+package forward
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// clientSubnet returns the request to send upstream, which carries the EDNS0 client subnet option as
+// configured with the ecs property. It also returns the client subnet option the client sent, if any.
+func (f *Forward) clientSubnet(state request.Request) (request.Request, *dns.EDNS0_SUBNET) {
+	client := edns.ClientSubnet(state.Req)
+
+	var e *dns.EDNS0_SUBNET
+	switch {
+	case f.ecs == ecsForward && client != nil && client.SourceNetmask == 0:
+		// The client doesn't want its subnet to be used, see RFC 7871, section 7.1.2.
+		return state, client
+
+	case f.ecs == ecsForward && client != nil:
+		prefix := f.ecsV4
+		if client.Family == 2 {
+			prefix = f.ecsV6
+		}
+		if client.SourceNetmask < prefix {
+			prefix = client.SourceNetmask
+		}
+		n := edns.Subnet(client, prefix)
+		if n == nil {
+			return state, client
+		}
+		e = &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: client.Family, SourceNetmask: prefix, Address: n.IP}
+
+	default:
+		ip := net.ParseIP(state.IP())
+		if ip == nil {
+			return state, client
+		}
+		e = edns.NewClientSubnet(ip, f.ecsV4, f.ecsV6)
+	}
+
+	m := *state.Req
+	m.Extra = make([]dns.RR, len(state.Req.Extra))
+	copy(m.Extra, state.Req.Extra)
+	edns.SetClientSubnet(&m, e)
+	return request.Request{W: state.W, Req: &m}, client
+}
+
+// clientSubnetResponse sets the client subnet option in ret back to the one the client sent, with the
+// scope prefix length the upstream returned.
+func clientSubnetResponse(ret *dns.Msg, client *dns.EDNS0_SUBNET) {
+	e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: client.Family, SourceNetmask: client.SourceNetmask, Address: client.Address}
+	if upstream := edns.ClientSubnet(ret); upstream != nil {
+		e.SourceScope = upstream.SourceScope
+	}
+	edns.SetClientSubnet(ret, e)
+}
+
+// Values for the ecs property.
+const (
+	ecsAdd     = "add"
+	ecsForward = "forward"
+)
+
+const (
+	defaultECSv4 = 24
+	defaultECSv6 = 56
+)
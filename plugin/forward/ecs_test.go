@@ -0,0 +1,85 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestClientSubnet(t *testing.T) {
+	var upstream string
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		upstream = ""
+		if e := edns.ClientSubnet(r); e != nil {
+			upstream = edns.Subnet(e, e.SourceNetmask).String()
+			edns.SetClientSubnet(ret, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: e.Family, SourceNetmask: e.SourceNetmask, SourceScope: 20, Address: e.Address})
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	tests := []struct {
+		mode     string
+		client   string // client subnet in the query, empty for none
+		upstream string // client subnet the upstream sees
+		reply    string // client subnet in the reply
+	}{
+		{ecsAdd, "", "10.240.0.0/24", "10.240.0.0/24"},
+		{ecsAdd, "192.0.2.128/25", "10.240.0.0/24", "192.0.2.128/25"},
+		{ecsForward, "", "10.240.0.0/24", "10.240.0.0/24"},
+		{ecsForward, "192.0.2.128/25", "192.0.2.0/24", "192.0.2.128/25"},
+		{ecsForward, "192.0.2.0/16", "192.0.0.0/16", "192.0.0.0/16"},
+		{ecsForward, "2001:db8::/48", "2001:db8::/48", "2001:db8::/48"},
+		{ecsForward, "2001:db8::/64", "2001:db8::/56", "2001:db8::/64"},
+		{ecsForward, "0.0.0.0/0", "0.0.0.0/0", "0.0.0.0/0"},
+	}
+
+	for i, tc := range tests {
+		f := New()
+		f.SetProxy(NewProxy(s.Addr, transport.DNS))
+		f.ecs, f.ecsV4, f.ecsV6 = tc.mode, defaultECSv4, defaultECSv6
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		if tc.client != "" {
+			ip, ipnet, _ := net.ParseCIDR(tc.client)
+			ones, _ := ipnet.Mask.Size()
+			edns.SetClientSubnet(m, edns.NewClientSubnet(ip, uint8(ones), uint8(ones)))
+		}
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+		}
+		f.OnShutdown()
+
+		if upstream != tc.upstream {
+			t.Errorf("Test %d: expected upstream to see %s, got %s", i, tc.upstream, upstream)
+		}
+		e := edns.ClientSubnet(rec.Msg)
+		if e == nil {
+			t.Errorf("Test %d: expected client subnet in reply", i)
+			continue
+		}
+		if x := edns.Subnet(e, e.SourceNetmask).String(); x != tc.reply {
+			t.Errorf("Test %d: expected %s in reply, got %s", i, tc.reply, x)
+		}
+		if e.SourceScope != 20 {
+			t.Errorf("Test %d: expected scope of the upstream in reply, got %d", i, e.SourceScope)
+		}
+		if tc.client == "" && edns.ClientSubnet(m) != nil {
+			t.Errorf("Test %d: expected query to be left alone", i)
+		}
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/debug"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -24,6 +26,8 @@ var log = clog.NewWithPlugin("forward")
 // Forward represents a plugin instance that can proxy requests to another (DNS) server. It has a list
 // of proxies each representing one upstream proxy.
 type Forward struct {
+	concurrent int64 // number of queries in flight, accessed atomically; first in the struct for 64 bit alignment
+
 	proxies    []*Proxy
 	p          Policy
 	hcInterval time.Duration
@@ -36,6 +40,12 @@ type Forward struct {
 	maxfails      uint32
 	expire        time.Duration
 
+	ecs   string // how to handle the client subnet, empty when disabled
+	ecsV4 uint8
+	ecsV6 uint8
+
+	maxConcurrent int64 // maximum number of queries in flight, 0 is unlimited
+
 	opts options // also here for testing
 
 	Next plugin.Handler
@@ -67,6 +77,20 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
 
+	count := atomic.AddInt64(&f.concurrent, 1)
+	defer atomic.AddInt64(&f.concurrent, -1)
+	RequestsInFlight.Inc()
+	defer RequestsInFlight.Dec()
+	if f.maxConcurrent > 0 && count > f.maxConcurrent {
+		MaxConcurrentRejectCount.Inc()
+		return dns.RcodeRefused, ErrLimitExceeded
+	}
+
+	var clientECS *dns.EDNS0_SUBNET
+	if f.ecs != "" {
+		state, clientECS = f.clientSubnet(state)
+	}
+
 	fails := 0
 	var span, child ot.Span
 	var upstreamErr error
@@ -107,9 +131,15 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			err error
 		)
 		opts := f.opts
+		badCookie := false
 		for {
 			ret, err = proxy.Connect(ctx, state, opts)
 			if err == nil {
+				// Retry once with the server cookie the upstream just handed out.
+				if ret.Rcode == dns.RcodeBadCookie && !badCookie {
+					badCookie = true
+					continue
+				}
 				break
 			}
 			if err == ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
@@ -152,15 +182,18 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			return 0, taperr
 		}
 
+		if clientECS != nil {
+			clientSubnetResponse(ret, clientECS)
+		}
 		w.WriteMsg(ret)
 		return 0, taperr
 	}
 
 	if upstreamErr != nil {
-		return dns.RcodeServerFailure, upstreamErr
+		return dns.RcodeServerFailure, edns.NewError(upstreamErr, edns.ExtendedErrorCodeNetworkError)
 	}
 
-	return dns.RcodeServerFailure, ErrNoHealthy
+	return dns.RcodeServerFailure, edns.NewError(ErrNoHealthy, edns.ExtendedErrorCodeNoReachableAuthority)
 }
 
 func (f *Forward) match(state request.Request) bool {
@@ -198,6 +231,8 @@ var (
 	ErrNoHealthy = errors.New("no healthy proxies")
 	// ErrNoForward means no forwarder defined.
 	ErrNoForward = errors.New("no forwarder defined")
+	// ErrLimitExceeded means more queries than max_concurrent are in flight.
+	ErrLimitExceeded = errors.New("concurrent queries exceeded maximum")
 	// ErrCachedClosed means cached connection was closed by peer.
 	ErrCachedClosed = errors.New("cached connection was closed by peer")
 )
@@ -0,0 +1,70 @@
+package forward
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.SetProxy(NewProxy(s.Addr, transport.DNS))
+	f.maxConcurrent = 1
+	defer f.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected query to be forwarded, got: %s", err)
+	}
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected an answer, got %v", rec.Msg)
+	}
+
+	// Pretend another query is in flight.
+	f.concurrent = 1
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, err := f.ServeDNS(context.TODO(), rec, m)
+	if err != ErrLimitExceeded {
+		t.Errorf("Expected %s, got: %v", ErrLimitExceeded, err)
+	}
+	if rcode != dns.RcodeRefused {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeRefused, rcode)
+	}
+	if rec.Msg != nil {
+		t.Errorf("Expected no reply to be written, got %v", rec.Msg)
+	}
+}
+
+func TestPad(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if p := pad(m); p != m {
+		t.Errorf("Expected query without OPT record to be returned as is")
+	}
+
+	m.SetEdns0(4096, false)
+	p := pad(m)
+	if p.Len()%edns.QueryBlockSize != 0 {
+		t.Errorf("Expected length to be a multiple of %d, got %d", edns.QueryBlockSize, p.Len())
+	}
+	if edns.Padding(m) != nil {
+		t.Errorf("Expected the original query not to be padded")
+	}
+}
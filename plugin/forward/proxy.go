@@ -22,6 +22,8 @@ type Proxy struct {
 	// health checking
 	probe  *up.Probe
 	health HealthChecker
+
+	cookie *cookie
 }
 
 // NewProxy returns a new proxy.
@@ -31,6 +33,7 @@ func NewProxy(addr, trans string) *Proxy {
 		fails:     0,
 		probe:     up.New(),
 		transport: newTransport(addr),
+		cookie:    newCookie(),
 	}
 	p.health = NewHealthChecker(trans)
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
@@ -38,7 +38,8 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(func() error {
-		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge)
+		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, CookieCount, HealthcheckFailureCount, SocketGauge,
+			RequestsInFlight, MaxConcurrentRejectCount)
 		return f.OnStartup()
 	})
 
@@ -220,6 +221,42 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
+	case "max_concurrent":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return fmt.Errorf("max_concurrent must be at least 1: %d", n)
+		}
+		f.maxConcurrent = int64(n)
+	case "ecs":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 3 {
+			return c.ArgErr()
+		}
+		if args[0] != ecsAdd && args[0] != ecsForward {
+			return c.Errf("unknown ecs mode '%s'", args[0])
+		}
+		f.ecs = args[0]
+		f.ecsV4, f.ecsV6 = defaultECSv4, defaultECSv6
+		if len(args) > 1 {
+			n, err := strconv.ParseUint(args[1], 10, 8)
+			if err != nil || n > 32 {
+				return c.Errf("invalid IPv4 source prefix length '%s'", args[1])
+			}
+			f.ecsV4 = uint8(n)
+		}
+		if len(args) > 2 {
+			n, err := strconv.ParseUint(args[2], 10, 8)
+			if err != nil || n > 128 {
+				return c.Errf("invalid IPv6 source prefix length '%s'", args[2])
+			}
+			f.ecsV6 = uint8(n)
+		}
 
 	default:
 		return c.Errf("unknown property '%s'", c.Val())
@@ -172,3 +172,72 @@ nameserver 10.10.255.253`), 0666); err != nil {
 		}
 	}
 }
+
+func TestSetupECS(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		mode      string
+		v4, v6    uint8
+	}{
+		{"forward . 127.0.0.1", false, "", 0, 0},
+		{"forward . 127.0.0.1 {\necs add\n}\n", false, ecsAdd, defaultECSv4, defaultECSv6},
+		{"forward . 127.0.0.1 {\necs forward 16\n}\n", false, ecsForward, 16, defaultECSv6},
+		{"forward . 127.0.0.1 {\necs forward 16 48\n}\n", false, ecsForward, 16, 48},
+		{"forward . 127.0.0.1 {\necs\n}\n", true, "", 0, 0},
+		{"forward . 127.0.0.1 {\necs strip\n}\n", true, "", 0, 0},
+		{"forward . 127.0.0.1 {\necs add 33\n}\n", true, "", 0, 0},
+		{"forward . 127.0.0.1 {\necs add 24 129\n}\n", true, "", 0, 0},
+		{"forward . 127.0.0.1 {\necs add 24 56 0\n}\n", true, "", 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if f.ecs != test.mode || f.ecsV4 != test.v4 || f.ecsV6 != test.v6 {
+			t.Errorf("Test %d: expected ecs %q %d %d, got %q %d %d", i, test.mode, test.v4, test.v6, f.ecs, f.ecsV4, f.ecsV6)
+		}
+	}
+}
+
+func TestSetupMaxConcurrent(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  int64
+	}{
+		{"forward . 127.0.0.1", false, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent 1000\n}\n", false, 1000},
+		{"forward . 127.0.0.1 {\nmax_concurrent\n}\n", true, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent 0\n}\n", true, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent many\n}\n", true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if f.maxConcurrent != test.expected {
+			t.Errorf("Test %d: expected max_concurrent %d, got %d", i, test.expected, f.maxConcurrent)
+		}
+	}
+}
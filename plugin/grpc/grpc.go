@@ -3,6 +3,8 @@ package grpc
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -16,6 +18,8 @@ import (
 // GRPC represents a plugin instance that can proxy requests to another (DNS) server via gRPC protocol.
 // It has a list of proxies each representing one upstream proxy.
 type GRPC struct {
+	concurrent int64 // number of queries in flight, accessed atomically; first in the struct for 64 bit alignment
+
 	proxies []*Proxy
 	p       Policy
 
@@ -25,6 +29,8 @@ type GRPC struct {
 	tlsConfig     *tls.Config
 	tlsServerName string
 
+	maxConcurrent int64 // maximum number of queries in flight, 0 is unlimited
+
 	Next plugin.Handler
 }
 
@@ -35,6 +41,15 @@ func (g *GRPC) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
 	}
 
+	count := atomic.AddInt64(&g.concurrent, 1)
+	defer atomic.AddInt64(&g.concurrent, -1)
+	RequestsInFlight.Inc()
+	defer RequestsInFlight.Dec()
+	if g.maxConcurrent > 0 && count > g.maxConcurrent {
+		MaxConcurrentRejectCount.Inc()
+		return dns.RcodeRefused, ErrLimitExceeded
+	}
+
 	var (
 		span, child ot.Span
 		ret         *dns.Msg
@@ -129,3 +144,6 @@ func (g *GRPC) isAllowedDomain(name string) bool {
 func (g *GRPC) list() []*Proxy { return g.p.List(g.proxies) }
 
 const defaultTimeout = 5 * time.Second
+
+// ErrLimitExceeded means more queries than max_concurrent are in flight.
+var ErrLimitExceeded = errors.New("concurrent queries exceeded maximum")
@@ -27,4 +27,16 @@ var (
 		Buckets:   plugin.TimeBuckets,
 		Help:      "Histogram of the time each request took.",
 	}, []string{"to"})
+	RequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "grpc",
+		Name:      "requests_in_flight",
+		Help:      "Gauge of requests that are being forwarded.",
+	})
+	MaxConcurrentRejectCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "grpc",
+		Name:      "max_concurrent_reject_count_total",
+		Help:      "Counter of requests refused because max_concurrent was reached.",
+	})
 )
@@ -3,6 +3,7 @@ package grpc
 import (
 	"crypto/tls"
 	"fmt"
+	"strconv"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -37,7 +38,7 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(func() error {
-		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration)
+		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, RequestsInFlight, MaxConcurrentRejectCount)
 		return nil
 	})
 
@@ -146,6 +147,18 @@ func parseBlock(c *caddyfile.Dispenser, g *GRPC) error {
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
+	case "max_concurrent":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return fmt.Errorf("max_concurrent must be at least 1: %d", n)
+		}
+		g.maxConcurrent = int64(n)
 	default:
 		if c.Val() != "}" {
 			return c.Errf("unknown property '%s'", c.Val())
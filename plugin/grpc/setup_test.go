@@ -151,3 +151,35 @@ nameserver 10.10.255.253`), 0666); err != nil {
 		}
 	}
 }
+
+func TestSetupMaxConcurrent(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  int64
+	}{
+		{"grpc . 127.0.0.1", false, 0},
+		{"grpc . 127.0.0.1 {\nmax_concurrent 1000\n}\n", false, 1000},
+		{"grpc . 127.0.0.1 {\nmax_concurrent\n}\n", true, 0},
+		{"grpc . 127.0.0.1 {\nmax_concurrent 0\n}\n", true, 0},
+		{"grpc . 127.0.0.1 {\nmax_concurrent many\n}\n", true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		g, err := parseGRPC(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if g.maxConcurrent != test.expected {
+			t.Errorf("Test %d: expected max_concurrent %d, got %d", i, test.expected, g.maxConcurrent)
+		}
+	}
+}
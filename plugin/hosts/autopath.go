@@ -0,0 +1,24 @@
+package hosts
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/autopath"
+	"github.com/coredns/coredns/request"
+)
+
+// AutoPath implements the AutoPathFunc call from the autopath plugin.
+// It returns the search path derived from the name of the client in the hosts file, or nil
+// when the client isn't listed.
+func (h Hosts) AutoPath(state request.Request) []string {
+	zone := plugin.Zones(h.Origins).Matches(state.Name())
+	if zone == "" {
+		return nil
+	}
+
+	for _, name := range h.LookupStaticAddr(state.IP()) {
+		if search := autopath.SearchPath(name, zone); search != nil {
+			return search
+		}
+	}
+	return nil
+}
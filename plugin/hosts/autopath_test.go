@@ -0,0 +1,41 @@
+package hosts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestAutoPath(t *testing.T) {
+	h := Hosts{
+		Next: test.ErrorHandler(),
+		Hostsfile: &Hostsfile{
+			Origins: []string{"example.org."},
+			hmap:    newHostsMap(),
+			options: newOptions(),
+		},
+	}
+	h.parseReader(strings.NewReader("10.240.0.1 web.prod.example.org\n10.240.0.2 other.example.org\n"))
+
+	tests := []struct {
+		qname  string
+		search []string
+	}{
+		{"db.prod.example.org.", []string{"prod.example.org.", "example.org.", ""}},
+		{"db.example.net.", nil},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		// test.ResponseWriter uses 10.240.0.1 as the client address.
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+		if got := h.AutoPath(state); !reflect.DeepEqual(got, tc.search) {
+			t.Errorf("Test %d, expected %v, got %v", i, tc.search, got)
+		}
+	}
+}
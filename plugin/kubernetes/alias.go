@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/request"
+
+	api "k8s.io/api/core/v1"
+)
+
+// HasEndpoints implements the Endpointer interface from the alias plugin. It returns false if
+// the name is a service without ready endpoints, or a service that does not exist.
+func (k *Kubernetes) HasEndpoints(state request.Request) bool {
+	zone := plugin.Zones(k.Zones).Matches(state.Name())
+	if zone == "" {
+		return true
+	}
+	state.Zone = zone
+
+	r, err := parseRequest(state)
+	if err != nil || r.podOrSvc != Svc || r.service == "" || r.namespace == "" {
+		return true
+	}
+	if r.service == "*" || r.namespace == "*" {
+		return true
+	}
+
+	for _, svc := range k.APIConn.SvcIndex(object.ServiceKey(r.service, r.namespace)) {
+		if svc.Type == api.ServiceTypeExternalName {
+			return true
+		}
+		for _, ep := range k.APIConn.EpIndex(object.EndpointsKey(svc.Name, svc.Namespace)) {
+			for _, sub := range ep.Subsets {
+				if len(sub.Addresses) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestHasEndpoints(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnServeTest{}
+
+	tests := []struct {
+		qname     string
+		endpoints bool
+	}{
+		{"svc1.testns.svc.cluster.local.", true},
+		{"svcempty.testns.svc.cluster.local.", false},
+		{"nosuchsvc.testns.svc.cluster.local.", false},
+		{"external.testns.svc.cluster.local.", true},
+		{"hdls1.testns.svc.cluster.local.", true},
+		{"svc.cluster.local.", true},
+		{"10-240-0-1.podns.pod.cluster.local.", true},
+		{"svc1.testns.svc.example.org.", true},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+		if got := k.HasEndpoints(state); got != tc.endpoints {
+			t.Errorf("Test %d: expected %t for %s, got %t", i, tc.endpoints, tc.qname, got)
+		}
+	}
+}
@@ -51,6 +51,10 @@ func New(addr string) *Metrics {
 	met.MustRegister(vars.RequestType)
 	met.MustRegister(vars.ResponseSize)
 	met.MustRegister(vars.ResponseRcode)
+	met.MustRegister(vars.RequestCookie)
+	met.MustRegister(vars.TCPConnectionsRejected)
+	met.MustRegister(vars.RequestsInFlight)
+	met.MustRegister(vars.RequestOverload)
 	met.MustRegister(vars.PluginEnabled)
 
 	return met
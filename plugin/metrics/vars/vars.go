@@ -60,6 +60,34 @@ var (
 		Help:      "Counter of response status codes.",
 	}, []string{"server", "zone", "rcode"})
 
+	RequestCookie = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "request_cookie_count_total",
+		Help:      "Counter of DNS requests with a cookie per validation result.",
+	}, []string{"server", "result"})
+
+	TCPConnectionsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "tcp_connections_rejected_total",
+		Help:      "Counter of TCP connections that are closed right away, because of a connection limit.",
+	}, []string{"server", "reason"})
+
+	RequestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "requests_in_flight",
+		Help:      "Gauge of DNS requests that are being handled.",
+	}, []string{"server"})
+
+	RequestOverload = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "request_overload_count_total",
+		Help:      "Counter of DNS requests refused because too many requests are in flight.",
+	}, []string{"server"})
+
 	Panic = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Name:      "panic_count_total",
@@ -0,0 +1,87 @@
+// Package adminapi serves the HTTP admin APIs of plugins, such as the ones of cache and erratic.
+package adminapi
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+
+	"github.com/mholt/caddy"
+)
+
+// Listener serves the admin API of a plugin on an address.
+type Listener struct {
+	addr    string
+	handler http.Handler
+
+	sync.Mutex
+	ln net.Listener
+}
+
+// New returns a Listener that serves h on addr once it is started.
+func New(addr string, h http.Handler) *Listener { return &Listener{addr: addr, handler: h} }
+
+// Start starts listening on the address and serving the API.
+func (l *Listener) Start() error {
+	ln, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	l.ln = ln
+	l.Unlock()
+
+	go func() { http.Serve(ln, l.handler) }()
+
+	return nil
+}
+
+// Stop closes the listener, it is a noop if l isn't listening.
+func (l *Listener) Stop() error {
+	l.Lock()
+	defer l.Unlock()
+	if l.ln == nil {
+		return nil
+	}
+	err := l.ln.Close()
+	l.ln = nil
+	return err
+}
+
+// Addr returns the address l is listening on, or nil if it isn't.
+func (l *Listener) Addr() net.Addr {
+	l.Lock()
+	defer l.Unlock()
+	if l.ln == nil {
+		return nil
+	}
+	return l.ln.Addr()
+}
+
+// Register serves the admin API h on addr while c runs. All plugin instances that register the
+// same addr in u share the API of the first one, which Register returns, so each instance can add
+// itself to it. Failing to listen on addr is logged, it doesn't stop CoreDNS from starting.
+func Register(c *caddy.Controller, u uniq.U, addr string, h http.Handler) http.Handler {
+	l := New(addr, h)
+	start := func() error {
+		if err := l.Start(); err != nil {
+			log.Errorf("Failed to start admin API on %s: %s", addr, err)
+		}
+		return nil
+	}
+	l = u.Set(addr, start, l).(*Listener)
+
+	stop := func() error {
+		u.Unset(addr)
+		return l.Stop()
+	}
+	c.OnStartup(func() error { return u.ForEach() })
+	c.OnRestart(stop)
+	c.OnFinalShutdown(stop)
+
+	return l.handler
+}
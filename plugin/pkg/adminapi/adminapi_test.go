@@ -0,0 +1,40 @@
+package adminapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestListener(t *testing.T) {
+	l := New("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	if l.Addr() != nil {
+		t.Fatalf("Expected no address before start, got %s", l.Addr())
+	}
+	if err := l.Start(); err != nil {
+		t.Fatalf("Unable to start the listener: %v", err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", l.Addr()))
+	if err != nil {
+		t.Fatalf("Unable to query the listener: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("Expected %q, got %q", "ok", body)
+	}
+
+	if err := l.Stop(); err != nil {
+		t.Errorf("Expected no error on stop, got %v", err)
+	}
+	if err := l.Stop(); err != nil {
+		t.Errorf("Expected stopping twice to be a noop, got %v", err)
+	}
+	if l.Addr() != nil {
+		t.Errorf("Expected no address after stop, got %s", l.Addr())
+	}
+}
@@ -1,11 +1,15 @@
 // Package cache implements a cache. The cache hold 256 shards, each shard
-// holds a cache: a map with a mutex. There is no fancy expunge algorithm, it
-// just randomly evicts elements when it gets full.
+// holds a cache: a map with a mutex. When a shard gets full an element is
+// evicted according to the eviction Policy of the cache, by default a random
+// element is evicted.
 package cache
 
 import (
+	"container/list"
 	"hash/fnv"
 	"sync"
+
+	"github.com/coredns/coredns/plugin/cache/freq"
 )
 
 // Hash returns the FNV hash of what.
@@ -20,16 +24,27 @@ type Cache struct {
 	shards [shardSize]*shard
 }
 
-// shard is a cache with random eviction.
+// shard is a cache with an eviction policy.
 type shard struct {
-	items map[uint64]interface{}
-	size  int
+	items  map[uint64]interface{}
+	size   int
+	policy Policy
+
+	// For LRU and TinyLFU: the keys ordered by use, the front is the most recently used.
+	lru   *list.List
+	elems map[uint64]*list.Element
+
+	// For TinyLFU: the access frequencies of recently seen keys, including the ones not in the shard.
+	freqs map[uint64]*freq.Freq
 
 	sync.RWMutex
 }
 
-// New returns a new cache.
-func New(size int) *Cache {
+// New returns a new cache with random eviction.
+func New(size int) *Cache { return NewWithPolicy(size, Random) }
+
+// NewWithPolicy returns a new cache that evicts elements according to policy.
+func NewWithPolicy(size int, policy Policy) *Cache {
 	ssize := size / shardSize
 	if ssize < 4 {
 		ssize = 4
@@ -39,7 +54,7 @@ func New(size int) *Cache {
 
 	// Initialize all the shards
 	for i := 0; i < shardSize; i++ {
-		c.shards[i] = newShard(ssize)
+		c.shards[i] = newShardWithPolicy(ssize, policy)
 	}
 	return c
 }
@@ -71,30 +86,96 @@ func (c *Cache) Len() int {
 	return l
 }
 
-// newShard returns a new shard with size.
-func newShard(size int) *shard { return &shard{items: make(map[uint64]interface{}), size: size} }
+// Walk walks each shard in the cache, see (*shard).Walk.
+func (c *Cache) Walk(f func(map[uint64]interface{}, uint64) bool) {
+	for _, s := range c.shards {
+		if !s.Walk(f) {
+			return
+		}
+	}
+}
+
+// newShard returns a new shard with size and random eviction.
+func newShard(size int) *shard { return newShardWithPolicy(size, Random) }
+
+// newShardWithPolicy returns a new shard with size that evicts according to policy.
+func newShardWithPolicy(size int, policy Policy) *shard {
+	s := &shard{items: make(map[uint64]interface{}), size: size, policy: policy}
+	if policy == LRU || policy == TinyLFU {
+		s.lru = list.New()
+		s.elems = make(map[uint64]*list.Element)
+	}
+	if policy == TinyLFU {
+		s.freqs = make(map[uint64]*freq.Freq)
+	}
+	return s
+}
 
 // Add adds element indexed by key into the cache. Any existing element is overwritten
 func (s *shard) Add(key uint64, el interface{}) {
-	l := s.Len()
-	if l+1 > s.size {
-		s.Evict()
+	if s.policy == Random {
+		l := s.Len()
+		if l+1 > s.size {
+			s.Evict()
+		}
+
+		s.Lock()
+		s.items[key] = el
+		s.Unlock()
+		return
 	}
 
 	s.Lock()
+	defer s.Unlock()
+
+	if e, ok := s.elems[key]; ok {
+		s.items[key] = el
+		s.lru.MoveToFront(e)
+		return
+	}
+
+	if len(s.items)+1 > s.size {
+		victim := s.lru.Back().Value.(uint64)
+		if s.policy == TinyLFU && !s.admit(key, victim) {
+			return
+		}
+		s.remove(victim)
+	}
+
 	s.items[key] = el
-	s.Unlock()
+	s.elems[key] = s.lru.PushFront(key)
 }
 
 // Remove removes the element indexed by key from the cache.
 func (s *shard) Remove(key uint64) {
 	s.Lock()
-	delete(s.items, key)
+	s.remove(key)
 	s.Unlock()
 }
 
-// Evict removes a random element from the cache.
+// remove removes the element indexed by key, the caller must hold the write lock.
+func (s *shard) remove(key uint64) {
+	delete(s.items, key)
+	if s.elems == nil {
+		return
+	}
+	if e, ok := s.elems[key]; ok {
+		s.lru.Remove(e)
+		delete(s.elems, key)
+	}
+}
+
+// Evict removes an element from the cache: a random one, or the least recently used one.
 func (s *shard) Evict() {
+	if s.policy != Random {
+		s.Lock()
+		if e := s.lru.Back(); e != nil {
+			s.remove(e.Value.(uint64))
+		}
+		s.Unlock()
+		return
+	}
+
 	hasKey := false
 	var key uint64
 
@@ -117,12 +198,56 @@ func (s *shard) Evict() {
 
 // Get looks up the element indexed under key.
 func (s *shard) Get(key uint64) (interface{}, bool) {
-	s.RLock()
+	if s.policy == Random {
+		s.RLock()
+		el, found := s.items[key]
+		s.RUnlock()
+		return el, found
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.policy == TinyLFU {
+		s.touch(key)
+	}
 	el, found := s.items[key]
-	s.RUnlock()
+	if found {
+		s.lru.MoveToFront(s.elems[key])
+	}
 	return el, found
 }
 
+// Walk calls f for each element in the shard, f is executed while holding the write lock,
+// so it may delete the element from the map. If f returns false the walk is stopped and
+// Walk returns false.
+func (s *shard) Walk(f func(map[uint64]interface{}, uint64) bool) bool {
+	s.RLock()
+	keys := make([]uint64, 0, len(s.items))
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	s.RUnlock()
+
+	for _, k := range keys {
+		s.Lock()
+		if _, ok := s.items[k]; !ok {
+			// Removed since we collected the keys.
+			s.Unlock()
+			continue
+		}
+		ok := f(s.items, k)
+		if _, found := s.items[k]; !found {
+			// f deleted the element, keep the LRU list in sync.
+			s.remove(k)
+		}
+		s.Unlock()
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // Len returns the current length of the cache.
 func (s *shard) Len() int {
 	s.RLock()
@@ -30,6 +30,37 @@ func TestCacheLen(t *testing.T) {
 	}
 }
 
+func TestCacheWalk(t *testing.T) {
+	c := New(4)
+	for i := uint64(0); i < 10; i++ {
+		c.Add(i, i)
+	}
+
+	seen := 0
+	c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+		if key%2 == 0 {
+			delete(items, key)
+		}
+		seen++
+		return true
+	})
+	if seen != 10 {
+		t.Fatalf("Walk should have seen %d elements, got %d", 10, seen)
+	}
+	if l := c.Len(); l != 5 {
+		t.Fatalf("Cache size should %d, got %d", 5, l)
+	}
+
+	seen = 0
+	c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+		seen++
+		return false
+	})
+	if seen != 1 {
+		t.Fatalf("Walk should have stopped after %d element, got %d", 1, seen)
+	}
+}
+
 func BenchmarkCache(b *testing.B) {
 	b.ReportAllocs()
 
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coredns/coredns/plugin/cache/freq"
+)
+
+// Policy is the eviction policy of a cache.
+type Policy int
+
+const (
+	// Random evicts a random element when a shard is full.
+	Random Policy = iota
+	// LRU evicts the least recently used element when a shard is full.
+	LRU
+	// TinyLFU evicts like LRU, but only admits a new element when it has been requested more
+	// often than the element it would evict. This keeps one-off lookups from pushing out
+	// popular elements.
+	TinyLFU
+)
+
+// ParsePolicy returns the Policy named by s.
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "random":
+		return Random, nil
+	case "lru":
+		return LRU, nil
+	case "tinylfu":
+		return TinyLFU, nil
+	}
+	return Random, fmt.Errorf("unknown eviction policy: %q", s)
+}
+
+func (p Policy) String() string {
+	switch p {
+	case Random:
+		return "random"
+	case LRU:
+		return "lru"
+	case TinyLFU:
+		return "tinylfu"
+	}
+	return "unknown"
+}
+
+// touch records an access of key and returns the number of accesses seen for it. The caller must
+// hold the write lock.
+func (s *shard) touch(key uint64) int {
+	now := time.Now()
+	f, ok := s.freqs[key]
+	if !ok {
+		// Age the frequencies by forgetting all of them once we track too many keys.
+		if len(s.freqs) >= s.size*freqFactor {
+			s.freqs = make(map[uint64]*freq.Freq)
+		}
+		f = freq.New(now)
+		s.freqs[key] = f
+	}
+	return f.Update(freqWindow, now)
+}
+
+// admit returns true if candidate should replace victim in the shard. The caller must hold the
+// write lock.
+func (s *shard) admit(candidate, victim uint64) bool {
+	c, v := 0, 0
+	if f, ok := s.freqs[candidate]; ok {
+		c = f.Hits()
+	}
+	if f, ok := s.freqs[victim]; ok {
+		v = f.Hits()
+	}
+	return c > v
+}
+
+const (
+	// freqFactor times the shard size is the number of keys for which we keep frequencies.
+	freqFactor = 8
+	// freqWindow is the maximum gap between two accesses for them to count towards the same frequency.
+	freqWindow = 5 * time.Minute
+)
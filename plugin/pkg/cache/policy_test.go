@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{Random, LRU, TinyLFU} {
+		p1, err := ParsePolicy(p.String())
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", p, err)
+		}
+		if p1 != p {
+			t.Errorf("Expected policy %s, got %s", p, p1)
+		}
+	}
+	if _, err := ParsePolicy("mru"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestShardEvictLRU(t *testing.T) {
+	s := newShardWithPolicy(2, LRU)
+	s.Add(1, 1)
+	s.Add(2, 2)
+	s.Get(1)
+	s.Add(3, 3)
+	// 2 is the least recently used and should be gone
+
+	if _, found := s.Get(2); found {
+		t.Fatal("Found item that should have been evicted")
+	}
+	if _, found := s.Get(1); !found {
+		t.Fatal("Failed to find recently used item")
+	}
+	if l := s.Len(); l != 2 {
+		t.Fatalf("Shard size should %d, got %d", 2, l)
+	}
+}
+
+func TestShardAdmitTinyLFU(t *testing.T) {
+	s := newShardWithPolicy(2, TinyLFU)
+	for _, k := range []uint64{1, 2} {
+		s.Get(k)
+		s.Add(k, k)
+	}
+	s.Get(1)
+	s.Get(2)
+	// 1 is the LRU victim and has been seen twice.
+
+	// 3 has been seen once, so it is not admitted.
+	s.Get(3)
+	s.Add(3, 3)
+	if _, found := s.items[3]; found {
+		t.Fatal("Found item that should not have been admitted")
+	}
+
+	// 3 has been seen twice as well.
+	s.Get(3)
+	s.Add(3, 3)
+	if _, found := s.items[3]; found {
+		t.Fatal("Found item that should not have been admitted")
+	}
+
+	// 3 has been seen more often than the victim.
+	s.Get(3)
+	s.Add(3, 3)
+	if _, found := s.items[3]; !found {
+		t.Fatal("Failed to find item that should have been admitted")
+	}
+	if _, found := s.items[1]; found {
+		t.Fatal("Found item that should have been evicted")
+	}
+}
+
+func TestShardWalkLRU(t *testing.T) {
+	s := newShardWithPolicy(4, LRU)
+	for k := uint64(1); k <= 4; k++ {
+		s.Add(k, k)
+	}
+	s.Walk(func(items map[uint64]interface{}, key uint64) bool {
+		delete(items, key)
+		return true
+	})
+	if s.lru.Len() != 0 || len(s.elems) != 0 {
+		t.Fatalf("Expected LRU list to be empty, got %d elements", s.lru.Len())
+	}
+}
+
+// zipfTrace returns n keys drawn from a Zipf distribution, the same keys on every call.
+func zipfTrace(n int) []uint64 {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.1, 1, 1<<20)
+	keys := make([]uint64, n)
+	for i := range keys {
+		keys[i] = z.Uint64()
+	}
+	return keys
+}
+
+// hitRatio replays keys against c and returns the hit ratio.
+func hitRatio(c *Cache, keys []uint64) float64 {
+	hits := 0
+	for _, k := range keys {
+		if _, ok := c.Get(k); ok {
+			hits++
+			continue
+		}
+		c.Add(k, k)
+	}
+	return float64(hits) / float64(len(keys))
+}
+
+func TestPolicyHitRatio(t *testing.T) {
+	keys := zipfTrace(200000)
+	random := hitRatio(NewWithPolicy(1024, Random), keys)
+	lru := hitRatio(NewWithPolicy(1024, LRU), keys)
+	tinylfu := hitRatio(NewWithPolicy(1024, TinyLFU), keys)
+	t.Logf("Hit ratio over %d lookups: random %.3f, lru %.3f, tinylfu %.3f", len(keys), random, lru, tinylfu)
+
+	if lru <= random {
+		t.Errorf("Expected LRU hit ratio (%.3f) to be higher than random (%.3f)", lru, random)
+	}
+	if tinylfu <= lru {
+		t.Errorf("Expected TinyLFU hit ratio (%.3f) to be higher than LRU (%.3f)", tinylfu, lru)
+	}
+}
+
+func BenchmarkPolicy(b *testing.B) {
+	keys := zipfTrace(1 << 16)
+	for _, p := range []Policy{Random, LRU, TinyLFU} {
+		b.Run(p.String(), func(b *testing.B) {
+			c := NewWithPolicy(1024, p)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				k := keys[i%len(keys)]
+				if _, ok := c.Get(k); !ok {
+					c.Add(k, k)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package edns
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ClientSubnet returns the EDNS0 client subnet option (RFC 7871) in m, or nil if m doesn't have one.
+func ClientSubnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, e := range o.Option {
+		if e, ok := e.(*dns.EDNS0_SUBNET); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// SetClientSubnet sets the client subnet option in m to e, or removes it when e is nil. Like
+// AddExtendedError the OPT record is replaced by a copy, if m doesn't have one it is added.
+func SetClientSubnet(m *dns.Msg, e *dns.EDNS0_SUBNET) {
+	i := len(m.Extra)
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	opt.SetUDPSize(dns.DefaultMsgSize)
+	for j, rr := range m.Extra {
+		if o, ok := rr.(*dns.OPT); ok {
+			opt.Hdr = o.Hdr
+			for _, e := range o.Option {
+				if e.Option() != dns.EDNS0SUBNET {
+					opt.Option = append(opt.Option, e)
+				}
+			}
+			i = j
+			break
+		}
+	}
+	if e != nil {
+		opt.Option = append(opt.Option, e)
+	}
+
+	if i == len(m.Extra) {
+		if e == nil {
+			return
+		}
+		m.Extra = append(m.Extra, opt)
+		return
+	}
+	m.Extra[i] = opt
+}
+
+// NewClientSubnet returns a client subnet option for ip, which is truncated to v4 or v6 bits
+// depending on its family.
+func NewClientSubnet(ip net.IP, v4, v6 uint8) *dns.EDNS0_SUBNET {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: v4, Address: ip4.Mask(net.CIDRMask(int(v4), net.IPv4len*8))}
+	}
+	return &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 2, SourceNetmask: v6, Address: ip.To16().Mask(net.CIDRMask(int(v6), net.IPv6len*8))}
+}
+
+// Subnet returns the network in e, masked to prefix bits. A nil network is returned when e holds an
+// unknown family or prefix is too long for it.
+func Subnet(e *dns.EDNS0_SUBNET, prefix uint8) *net.IPNet {
+	bits := net.IPv4len * 8
+	ip := e.Address.To4()
+	if e.Family == 2 {
+		bits = net.IPv6len * 8
+		ip = e.Address.To16()
+	}
+	if (e.Family != 1 && e.Family != 2) || ip == nil || int(prefix) > bits {
+		return nil
+	}
+	mask := net.CIDRMask(int(prefix), bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+}
@@ -0,0 +1,59 @@
+package edns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestClientSubnet(t *testing.T) {
+	req := ednsMsg()
+	o := req.Extra[0].(*dns.OPT)
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Extra = []dns.RR{o} // share the OPT record, like request.SizeAndDo does
+
+	SetClientSubnet(m, NewClientSubnet(net.ParseIP("192.0.2.42"), 24, 56))
+	if len(o.Option) != 0 {
+		t.Errorf("Expected OPT record of the request to be left alone")
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Failed to pack message: %s", err)
+	}
+	m = new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("Failed to unpack message: %s", err)
+	}
+
+	e := ClientSubnet(m)
+	if e == nil {
+		t.Fatal("Expected client subnet option")
+	}
+	if x := Subnet(e, e.SourceNetmask).String(); x != "192.0.2.0/24" {
+		t.Errorf("Expected %s, got %s", "192.0.2.0/24", x)
+	}
+	if x := Subnet(e, 16).String(); x != "192.0.0.0/16" {
+		t.Errorf("Expected %s, got %s", "192.0.0.0/16", x)
+	}
+	if Subnet(e, 33) != nil {
+		t.Errorf("Expected no subnet for prefix length 33")
+	}
+
+	SetClientSubnet(m, nil)
+	if ClientSubnet(m) != nil {
+		t.Errorf("Expected client subnet option to be removed")
+	}
+}
+
+func TestNewClientSubnet(t *testing.T) {
+	e := NewClientSubnet(net.ParseIP("2001:db8:1:2::1"), 24, 56)
+	if e.Family != 2 || e.SourceNetmask != 56 {
+		t.Errorf("Expected family 2 with source prefix length 56, got %d and %d", e.Family, e.SourceNetmask)
+	}
+	if x := Subnet(e, e.SourceNetmask).String(); x != "2001:db8:1::/56" {
+		t.Errorf("Expected %s, got %s", "2001:db8:1::/56", x)
+	}
+}
@@ -0,0 +1,149 @@
+package edns
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// EDNS0EDE is the option code of Extended DNS Errors, see RFC 8914.
+const EDNS0EDE = 15
+
+// Extended DNS Error info codes from RFC 8914.
+const (
+	ExtendedErrorCodeOther uint16 = iota
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm
+	ExtendedErrorCodeUnsupportedDSDigestType
+	ExtendedErrorCodeStaleAnswer
+	ExtendedErrorCodeForgedAnswer
+	ExtendedErrorCodeDNSSECIndeterminate
+	ExtendedErrorCodeDNSSECBogus
+	ExtendedErrorCodeSignatureExpired
+	ExtendedErrorCodeSignatureNotYetValid
+	ExtendedErrorCodeDNSKEYMissing
+	ExtendedErrorCodeRRSIGsMissing
+	ExtendedErrorCodeNoZoneKeyBitSet
+	ExtendedErrorCodeNSECMissing
+	ExtendedErrorCodeCachedError
+	ExtendedErrorCodeNotReady
+	ExtendedErrorCodeBlocked
+	ExtendedErrorCodeCensored
+	ExtendedErrorCodeFiltered
+	ExtendedErrorCodeProhibited
+	ExtendedErrorCodeStaleNXDOMAINAnswer
+	ExtendedErrorCodeNotAuthoritative
+	ExtendedErrorCodeNotSupported
+	ExtendedErrorCodeNoReachableAuthority
+	ExtendedErrorCodeNetworkError
+	ExtendedErrorCodeInvalidData
+)
+
+// ExtendedErrorCodeToString maps info codes to their textual representation.
+var ExtendedErrorCodeToString = map[uint16]string{
+	ExtendedErrorCodeOther:                      "Other",
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm: "Unsupported DNSKEY Algorithm",
+	ExtendedErrorCodeUnsupportedDSDigestType:    "Unsupported DS Digest Type",
+	ExtendedErrorCodeStaleAnswer:                "Stale Answer",
+	ExtendedErrorCodeForgedAnswer:               "Forged Answer",
+	ExtendedErrorCodeDNSSECIndeterminate:        "DNSSEC Indeterminate",
+	ExtendedErrorCodeDNSSECBogus:                "DNSSEC Bogus",
+	ExtendedErrorCodeSignatureExpired:           "Signature Expired",
+	ExtendedErrorCodeSignatureNotYetValid:       "Signature Not Yet Valid",
+	ExtendedErrorCodeDNSKEYMissing:              "DNSKEY Missing",
+	ExtendedErrorCodeRRSIGsMissing:              "RRSIGs Missing",
+	ExtendedErrorCodeNoZoneKeyBitSet:            "No Zone Key Bit Set",
+	ExtendedErrorCodeNSECMissing:                "NSEC Missing",
+	ExtendedErrorCodeCachedError:                "Cached Error",
+	ExtendedErrorCodeNotReady:                   "Not Ready",
+	ExtendedErrorCodeBlocked:                    "Blocked",
+	ExtendedErrorCodeCensored:                   "Censored",
+	ExtendedErrorCodeFiltered:                   "Filtered",
+	ExtendedErrorCodeProhibited:                 "Prohibited",
+	ExtendedErrorCodeStaleNXDOMAINAnswer:        "Stale NXDOMAIN Answer",
+	ExtendedErrorCodeNotAuthoritative:           "Not Authoritative",
+	ExtendedErrorCodeNotSupported:               "Not Supported",
+	ExtendedErrorCodeNoReachableAuthority:       "No Reachable Authority",
+	ExtendedErrorCodeNetworkError:               "Network Error",
+	ExtendedErrorCodeInvalidData:                "Invalid Data",
+}
+
+// ExtendedError is an Extended DNS Error.
+type ExtendedError struct {
+	InfoCode  uint16
+	ExtraText string
+}
+
+// String returns the info code and its textual representation, followed by the extra text, if any.
+func (e ExtendedError) String() string {
+	s := fmt.Sprintf("EDE %d", e.InfoCode)
+	if t, ok := ExtendedErrorCodeToString[e.InfoCode]; ok {
+		s += " (" + t + ")"
+	}
+	if e.ExtraText != "" {
+		s += ": " + e.ExtraText
+	}
+	return s
+}
+
+// Option returns e as an EDNS0 option.
+func (e ExtendedError) Option() dns.EDNS0 {
+	b := make([]byte, 2, 2+len(e.ExtraText))
+	binary.BigEndian.PutUint16(b, e.InfoCode)
+	return &dns.EDNS0_LOCAL{Code: EDNS0EDE, Data: append(b, e.ExtraText...)}
+}
+
+// Error is an error that carries an Extended DNS Error. Plugins return it from ServeDNS, together with
+// an rcode that makes the server write the reply, to have the extended error added to that reply.
+type Error struct {
+	Err error
+	ExtendedError
+}
+
+// NewError returns an Error for err with info code code. The extra text is taken from err.
+func NewError(err error, code uint16) *Error {
+	return &Error{Err: err, ExtendedError: ExtendedError{InfoCode: code, ExtraText: err.Error()}}
+}
+
+// Error implements the error interface, it returns the text of the underlying error.
+func (e *Error) Error() string { return e.Err.Error() }
+
+// AddExtendedError adds e to the OPT record of m, an OPT record is added if m doesn't have one. The
+// OPT record is replaced by a copy, so an OPT record shared with the request is left alone.
+func AddExtendedError(m *dns.Msg, e ExtendedError) {
+	i := len(m.Extra)
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	for j, rr := range m.Extra {
+		if o, ok := rr.(*dns.OPT); ok {
+			opt.Hdr = o.Hdr
+			opt.Option = make([]dns.EDNS0, len(o.Option), len(o.Option)+1)
+			copy(opt.Option, o.Option)
+			i = j
+			break
+		}
+	}
+	opt.Option = append(opt.Option, e.Option())
+
+	if i == len(m.Extra) {
+		m.Extra = append(m.Extra, opt)
+		return
+	}
+	m.Extra[i] = opt
+}
+
+// ExtendedErrors returns the Extended DNS Errors in m.
+func ExtendedErrors(m *dns.Msg) []ExtendedError {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	var ede []ExtendedError
+	for _, e := range o.Option {
+		l, ok := e.(*dns.EDNS0_LOCAL)
+		if !ok || l.Code != EDNS0EDE || len(l.Data) < 2 {
+			continue
+		}
+		ede = append(ede, ExtendedError{InfoCode: binary.BigEndian.Uint16(l.Data), ExtraText: string(l.Data[2:])})
+	}
+	return ede
+}
@@ -0,0 +1,66 @@
+package edns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestExtendedError(t *testing.T) {
+	req := ednsMsg()
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	o := req.Extra[0].(*dns.OPT)
+	m.Extra = []dns.RR{o} // share the OPT record, like request.SizeAndDo does
+
+	AddExtendedError(m, ExtendedError{InfoCode: ExtendedErrorCodeNetworkError, ExtraText: "i/o timeout"})
+	AddExtendedError(m, ExtendedError{InfoCode: ExtendedErrorCodeCachedError})
+
+	if len(o.Option) != 0 {
+		t.Errorf("Expected OPT record of the request to be left alone")
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Failed to pack message: %s", err)
+	}
+	m = new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("Failed to unpack message: %s", err)
+	}
+
+	ede := ExtendedErrors(m)
+	if len(ede) != 2 {
+		t.Fatalf("Expected 2 extended errors, got %d", len(ede))
+	}
+	if x := ede[0].String(); x != "EDE 23 (Network Error): i/o timeout" {
+		t.Errorf("Expected %q, got %q", "EDE 23 (Network Error): i/o timeout", x)
+	}
+	if x := ede[1].String(); x != "EDE 13 (Cached Error)" {
+		t.Errorf("Expected %q, got %q", "EDE 13 (Cached Error)", x)
+	}
+}
+
+func TestExtendedErrorNoOPT(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	AddExtendedError(m, ExtendedError{InfoCode: ExtendedErrorCodeBlocked})
+	if m.IsEdns0() == nil {
+		t.Fatal("Expected OPT record to be added")
+	}
+	if ede := ExtendedErrors(m); len(ede) != 1 || ede[0].InfoCode != ExtendedErrorCodeBlocked {
+		t.Errorf("Expected Blocked extended error, got %v", ede)
+	}
+}
+
+func TestError(t *testing.T) {
+	err := NewError(errors.New("no healthy proxies"), ExtendedErrorCodeNoReachableAuthority)
+	if err.Error() != "no healthy proxies" {
+		t.Errorf("Expected error text to be unchanged, got %q", err.Error())
+	}
+	if err.ExtraText != "no healthy proxies" {
+		t.Errorf("Expected extra text to be the error text, got %q", err.ExtraText)
+	}
+}
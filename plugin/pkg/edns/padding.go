@@ -0,0 +1,74 @@
+package edns
+
+import "github.com/miekg/dns"
+
+// Block sizes of the block-length padding strategy recommended by RFC 8467.
+const (
+	QueryBlockSize    = 128
+	ResponseBlockSize = 468
+)
+
+// Padding returns the EDNS0 padding option (RFC 7830) in m, or nil if m doesn't have one.
+func Padding(m *dns.Msg) *dns.EDNS0_PADDING {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, e := range o.Option {
+		if e, ok := e.(*dns.EDNS0_PADDING); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// Pad adds a padding option to m that makes the length of m a multiple of block, any padding m already
+// has is replaced. The padding is cut short if m would become larger than max. Only messages with an
+// OPT record can be padded, other messages are left alone.
+func Pad(m *dns.Msg, block, max int) {
+	if !setPadding(m, nil) {
+		return
+	}
+	l := m.Len() + 4 // option code and length
+	n := 0
+	if r := l % block; r != 0 {
+		n = block - r
+	}
+	if l+n > max {
+		n = max - l
+	}
+	if n < 0 {
+		return
+	}
+	setPadding(m, &dns.EDNS0_PADDING{Padding: make([]byte, n)})
+}
+
+// RemovePadding removes the padding option from m.
+func RemovePadding(m *dns.Msg) {
+	if Padding(m) != nil {
+		setPadding(m, nil)
+	}
+}
+
+// setPadding sets the padding option in m to e, or removes it when e is nil. Like SetClientSubnet the
+// OPT record is replaced by a copy. It returns false if m doesn't have an OPT record.
+func setPadding(m *dns.Msg, e *dns.EDNS0_PADDING) bool {
+	for i, rr := range m.Extra {
+		o, ok := rr.(*dns.OPT)
+		if !ok {
+			continue
+		}
+		opt := &dns.OPT{Hdr: o.Hdr}
+		for _, e := range o.Option {
+			if e.Option() != dns.EDNS0PADDING {
+				opt.Option = append(opt.Option, e)
+			}
+		}
+		if e != nil {
+			opt.Option = append(opt.Option, e)
+		}
+		m.Extra[i] = opt
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package edns
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		block, max int
+		expected   int // length of the padded message
+	}{
+		{QueryBlockSize, dns.MaxMsgSize, 128},
+		{ResponseBlockSize, dns.MaxMsgSize, 468},
+		{ResponseBlockSize, 100, 100},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_PADDING{Padding: make([]byte, 1000)})
+
+		Pad(m, tc.block, tc.max)
+		if l := m.Len(); l != tc.expected {
+			t.Errorf("Test %d: expected length %d, got %d", i, tc.expected, l)
+		}
+		if len(m.IsEdns0().Option) != 1 {
+			t.Errorf("Test %d: expected 1 option, got %d", i, len(m.IsEdns0().Option))
+		}
+	}
+}
+
+func TestPadTooLarge(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+
+	Pad(m, ResponseBlockSize, 20)
+	if Padding(m) != nil {
+		t.Errorf("Expected no padding when the message is larger than max")
+	}
+}
+
+func TestPadNoOPT(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	Pad(m, QueryBlockSize, dns.MaxMsgSize)
+	if len(m.Extra) != 0 {
+		t.Errorf("Expected no OPT record to be added, got %v", m.Extra)
+	}
+}
+
+func TestRemovePadding(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	o := m.IsEdns0()
+	o.Option = append(o.Option, &dns.EDNS0_PADDING{Padding: make([]byte, 10)})
+
+	RemovePadding(m)
+	if Padding(m) != nil {
+		t.Errorf("Expected padding to be removed")
+	}
+	if len(o.Option) != 1 {
+		t.Errorf("Expected the original OPT record to be left alone")
+	}
+}
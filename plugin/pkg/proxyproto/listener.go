@@ -0,0 +1,97 @@
+package proxyproto
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Listener wraps a net.Listener. Connections from trusted sources are returned as a *Conn.
+type Listener struct {
+	net.Listener
+	*Config
+}
+
+// NewListener returns a new Listener that wraps l.
+func NewListener(l net.Listener, c *Config) *Listener { return &Listener{Listener: l, Config: c} }
+
+// Accept implements the net.Listener interface.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.Trusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, r: bufio.NewReader(conn), timeout: l.Timeout}, nil
+}
+
+// File returns a copy of the underlying file descriptor, this allows for graceful reloads.
+func (l *Listener) File() (*os.File, error) {
+	f, ok := l.Listener.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return nil, errors.New("listener does not have a file descriptor")
+	}
+	return f.File()
+}
+
+// Conn is a connection that starts with a PROXY protocol header. The header is read on the first call
+// to Read or RemoteAddr, so a slow client doesn't hold up Accept.
+type Conn struct {
+	net.Conn
+	r        *bufio.Reader
+	timeout  time.Duration
+	deadline time.Time // read deadline set by the caller, restored after reading the header
+
+	once sync.Once
+	src  net.Addr
+	err  error
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer func() { c.Conn.SetReadDeadline(c.deadline) }()
+	}
+	c.src, _, c.err = Read(c.r)
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// Read implements the net.Conn interface.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// SetDeadline implements the net.Conn interface.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements the net.Conn interface.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+// RemoteAddr implements the net.Conn interface. It returns the source address from the PROXY
+// protocol header, or the address of the load balancer if the header doesn't have one.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
@@ -0,0 +1,73 @@
+package proxyproto
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	_, n, _ := net.ParseCIDR("127.0.0.0/8")
+	pl := NewListener(l, &Config{Allow: []*net.IPNet{n}, Timeout: time.Second})
+	defer pl.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		c.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 53000 53\r\nhello"))
+		c.Close()
+	}()
+
+	c, err := pl.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %s", err)
+	}
+	defer c.Close()
+
+	if x := c.RemoteAddr().String(); x != "192.0.2.1:53000" {
+		t.Errorf("Expected remote address 192.0.2.1:53000, got %s", x)
+	}
+	buf, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatalf("Failed to read: %s", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("Expected %q, got %q", "hello", buf)
+	}
+}
+
+func TestListenerUntrusted(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	pl := NewListener(l, &Config{Allow: []*net.IPNet{n}, Timeout: time.Second})
+	defer pl.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		c.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 53000 53\r\n"))
+		c.Close()
+	}()
+
+	c, err := pl.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %s", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.(*Conn); ok {
+		t.Errorf("Expected connection from untrusted source not to be wrapped")
+	}
+}
@@ -0,0 +1,191 @@
+// Package proxyproto implements the PROXY protocol, version 1 and 2, as defined by HAProxy. Load balancers
+// use it to pass on the address of the client, which is otherwise lost.
+//
+// See https://www.haproxy.org/download/2.0/doc/proxy-protocol.txt.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the sources that are allowed to send a PROXY protocol header.
+type Config struct {
+	// Allow holds the networks of the trusted sources, i.e. the load balancers. Connections and
+	// packets from these sources must start with a PROXY protocol header, others are left alone.
+	Allow []*net.IPNet
+	// Timeout is the time a connection has to send its header.
+	Timeout time.Duration
+}
+
+// Trusted returns true if addr is in one of the allowed networks.
+func (c *Config) Trusted(addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+	for _, n := range c.Allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	v1MaxLen    = 107 // including the CRLF
+	v2HeaderLen = 16
+)
+
+var (
+	// ErrNoHeader is returned when data doesn't start with a PROXY protocol header.
+	ErrNoHeader = errors.New("no PROXY protocol header")
+	// ErrInvalidHeader is returned for a PROXY protocol header that can't be parsed.
+	ErrInvalidHeader = errors.New("invalid PROXY protocol header")
+)
+
+// Parse parses the PROXY protocol header at the start of b. It returns the source and destination
+// address and the length of the header. For a header that doesn't carry addresses, like the LOCAL
+// command used for health checks, the addresses are nil. The addresses are a *net.TCPAddr or a
+// *net.UDPAddr depending on the protocol of the proxied connection.
+func Parse(b []byte) (src, dst net.Addr, n int, err error) {
+	switch {
+	case bytes.HasPrefix(b, v2Signature):
+		return parseV2(b)
+	case bytes.HasPrefix(b, v1Prefix):
+		return parseV1(b)
+	}
+	return nil, nil, 0, ErrNoHeader
+}
+
+// Read reads a PROXY protocol header from r, see Parse. Only the header is consumed from r.
+func Read(r *bufio.Reader) (src, dst net.Addr, err error) {
+	b, err := r.Peek(len(v1Prefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	l := 0
+	switch {
+	case bytes.Equal(b, v1Prefix):
+		// The header is short, peek a byte at a time until we see the CRLF.
+		for l = len(v1Prefix); !bytes.HasSuffix(b, []byte("\r\n")); l++ {
+			if l >= v1MaxLen {
+				return nil, nil, ErrInvalidHeader
+			}
+			if b, err = r.Peek(l + 1); err != nil {
+				return nil, nil, err
+			}
+		}
+	case bytes.HasPrefix(v2Signature, b):
+		if b, err = r.Peek(v2HeaderLen); err != nil {
+			return nil, nil, err
+		}
+		l = v2HeaderLen + int(binary.BigEndian.Uint16(b[14:]))
+	default:
+		return nil, nil, ErrNoHeader
+	}
+
+	if b, err = r.Peek(l); err != nil {
+		return nil, nil, err
+	}
+	src, dst, n, err := Parse(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Discard(n)
+	return src, dst, nil
+}
+
+// parseV1 parses a human readable version 1 header: "PROXY TCP4 192.0.2.1 192.0.2.2 53000 53\r\n".
+func parseV1(b []byte) (src, dst net.Addr, n int, err error) {
+	if len(b) > v1MaxLen {
+		b = b[:v1MaxLen]
+	}
+	i := bytes.Index(b, []byte("\r\n"))
+	if i < 0 {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+	n = i + 2
+
+	f := strings.Split(string(b[:i]), " ")
+	if len(f) >= 2 && f[1] == "UNKNOWN" {
+		return nil, nil, n, nil
+	}
+	if len(f) != 6 || (f[1] != "TCP4" && f[1] != "TCP6") {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+	srcIP, dstIP := net.ParseIP(f[2]), net.ParseIP(f[3])
+	if srcIP == nil || dstIP == nil || (srcIP.To4() != nil) != (f[1] == "TCP4") {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+	srcPort, err1 := strconv.ParseUint(f[4], 10, 16)
+	dstPort, err2 := strconv.ParseUint(f[5], 10, 16)
+	if err1 != nil || err2 != nil {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+	return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, &net.TCPAddr{IP: dstIP, Port: int(dstPort)}, n, nil
+}
+
+// parseV2 parses a binary version 2 header.
+func parseV2(b []byte) (src, dst net.Addr, n int, err error) {
+	if len(b) < v2HeaderLen {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+	n = v2HeaderLen + int(binary.BigEndian.Uint16(b[14:]))
+	if len(b) < n || b[12]>>4 != 2 {
+		return nil, nil, 0, ErrInvalidHeader
+	}
+
+	switch b[12] & 0xf {
+	case 0: // LOCAL
+		return nil, nil, n, nil
+	case 1: // PROXY
+	default:
+		return nil, nil, 0, ErrInvalidHeader
+	}
+
+	addr := b[v2HeaderLen:n]
+	var srcIP, dstIP net.IP
+	var ports []byte
+	switch b[13] >> 4 {
+	case 1: // AF_INET
+		if len(addr) < 12 {
+			return nil, nil, 0, ErrInvalidHeader
+		}
+		srcIP, dstIP, ports = net.IP(addr[0:4]), net.IP(addr[4:8]), addr[8:12]
+	case 2: // AF_INET6
+		if len(addr) < 36 {
+			return nil, nil, 0, ErrInvalidHeader
+		}
+		srcIP, dstIP, ports = net.IP(addr[0:16]), net.IP(addr[16:32]), addr[32:36]
+	default: // AF_UNSPEC and AF_UNIX, there is no address we can use.
+		return nil, nil, n, nil
+	}
+	// Copy the addresses, b is likely a buffer that is reused.
+	srcIP, dstIP = append(net.IP(nil), srcIP...), append(net.IP(nil), dstIP...)
+	srcPort, dstPort := int(binary.BigEndian.Uint16(ports)), int(binary.BigEndian.Uint16(ports[2:]))
+
+	switch b[13] & 0xf {
+	case 1: // STREAM
+		return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, n, nil
+	case 2: // DGRAM
+		return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}, n, nil
+	}
+	return nil, nil, n, nil
+}
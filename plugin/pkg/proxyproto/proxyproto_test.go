@@ -0,0 +1,107 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+)
+
+// v2Header returns a version 2 PROXY header for a STREAM (or DGRAM) connection from src to dst.
+func v2Header(src, dst string, srcPort, dstPort int, dgram bool) []byte {
+	s, d := net.ParseIP(src), net.ParseIP(dst)
+	fam := byte(0x21) // AF_INET6, STREAM
+	if s4 := s.To4(); s4 != nil {
+		fam = 0x11
+		s, d = s4, d.To4()
+	}
+	if dgram {
+		fam++
+	}
+	addr := append(append([]byte{}, s...), d...)
+	addr = append(addr, byte(srcPort>>8), byte(srcPort), byte(dstPort>>8), byte(dstPort))
+
+	b := append([]byte{}, v2Signature...)
+	b = append(b, 0x21, fam, byte(len(addr)>>8), byte(len(addr)))
+	return append(b, addr...)
+}
+
+func TestParse(t *testing.T) {
+	local := append(append([]byte{}, v2Signature...), 0x20, 0x00, 0x00, 0x00)
+	tests := []struct {
+		header []byte
+		src    string // empty for no address
+		err    bool
+	}{
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 53000 53\r\n"), "192.0.2.1:53000", false},
+		{[]byte("PROXY TCP6 2001:db8::1 2001:db8::2 53000 53\r\n"), "[2001:db8::1]:53000", false},
+		{[]byte("PROXY UNKNOWN\r\n"), "", false},
+		{[]byte("PROXY TCP4 2001:db8::1 192.0.2.2 53000 53\r\n"), "", true},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 53000\r\n"), "", true},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 53000 53"), "", true},
+		{[]byte("PROXY UDP4 192.0.2.1 192.0.2.2 53000 53\r\n"), "", true},
+		{v2Header("192.0.2.1", "192.0.2.2", 53000, 53, false), "192.0.2.1:53000", false},
+		{v2Header("2001:db8::1", "2001:db8::2", 53000, 53, true), "[2001:db8::1]:53000", false},
+		{local, "", false},
+		{v2Header("192.0.2.1", "192.0.2.2", 53000, 53, false)[:20], "", true},
+		{[]byte("GET / HTTP/1.0\r\n"), "", true},
+	}
+
+	for i, tc := range tests {
+		// Add a DNS message after the header, it should not be consumed.
+		b := append(append([]byte{}, tc.header...), 0, 12)
+
+		src, _, n, err := Parse(b)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if n != len(tc.header) {
+			t.Errorf("Test %d: expected header length %d, got %d", i, len(tc.header), n)
+		}
+		if src == nil && tc.src != "" || src != nil && src.String() != tc.src {
+			t.Errorf("Test %d: expected source %q, got %v", i, tc.src, src)
+		}
+
+		r := bufio.NewReader(bytes.NewReader(b))
+		src1, _, err := Read(r)
+		if err != nil {
+			t.Errorf("Test %d: expected no error reading header, got %s", i, err)
+			continue
+		}
+		if src1 == nil && src != nil || src1 != nil && src1.String() != src.String() {
+			t.Errorf("Test %d: expected source %v reading header, got %v", i, src, src1)
+		}
+		if r.Buffered() != 2 {
+			t.Errorf("Test %d: expected only the header to be read, %d bytes left", i, r.Buffered())
+		}
+	}
+}
+
+func TestParseDgram(t *testing.T) {
+	src, _, _, err := Parse(v2Header("192.0.2.1", "192.0.2.2", 53000, 53, true))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, ok := src.(*net.UDPAddr); !ok {
+		t.Errorf("Expected a *net.UDPAddr, got %T", src)
+	}
+}
+
+func TestTrusted(t *testing.T) {
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	c := &Config{Allow: []*net.IPNet{n}}
+
+	if !c.Trusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}) {
+		t.Errorf("Expected 10.1.2.3 to be trusted")
+	}
+	if c.Trusted(&net.UDPAddr{IP: net.ParseIP("192.0.2.1")}) {
+		t.Errorf("Expected 192.0.2.1 not to be trusted")
+	}
+}
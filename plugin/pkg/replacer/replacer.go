@@ -8,6 +8,7 @@ import (
 
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -34,6 +35,7 @@ var labels = []string{
 	headerReplacer + "opcode}",
 	headerReplacer + "do}",
 	headerReplacer + "bufsize}",
+	headerReplacer + "ecs}",
 	// Recorded replacements.
 	"{rcode}",
 	"{rsize}",
@@ -69,6 +71,13 @@ func value(state request.Request, rr *dnstest.Recorder, label string) string {
 		return boolToString(state.Do())
 	case headerReplacer + "bufsize}":
 		return strconv.Itoa(state.Size())
+	case headerReplacer + "ecs}":
+		if e := edns.ClientSubnet(state.Req); e != nil {
+			if n := edns.Subnet(e, e.SourceNetmask); n != nil {
+				return n.String()
+			}
+		}
+		return EmptyValue
 	// Recorded replacements.
 	case "{rcode}":
 		if rr == nil {
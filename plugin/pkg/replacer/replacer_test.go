@@ -2,10 +2,12 @@ package replacer
 
 import (
 	"context"
+	"net"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
 
@@ -59,6 +61,7 @@ func TestLabels(t *testing.T) {
 		headerReplacer + "opcode}":  "0",
 		headerReplacer + "do}":      "false",
 		headerReplacer + "bufsize}": "512",
+		headerReplacer + "ecs}":     EmptyValue,
 		"{rcode}":                   "NOERROR",
 		"{rsize}":                   "29",
 		"{duration}":                "0",
@@ -82,6 +85,18 @@ func TestLabels(t *testing.T) {
 	}
 }
 
+func TestClientSubnet(t *testing.T) {
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	edns.SetClientSubnet(r, edns.NewClientSubnet(net.ParseIP("192.0.2.42"), 24, 56))
+	state := request.Request{W: w, Req: r}
+
+	if x := New().Replace(context.TODO(), state, nil, "{>ecs}"); x != "192.0.2.0/24" {
+		t.Errorf("Expected client subnet to be 192.0.2.0/24, got %q", x)
+	}
+}
+
 func BenchmarkReplacer(b *testing.B) {
 	w := dnstest.NewRecorder(&test.ResponseWriter{})
 	r := new(dns.Msg)
@@ -0,0 +1,186 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Certificates holds server certificates loaded from PEM files. It picks the certificate for a
+// connection based on the server name the client asks for (SNI) and can reload the files when they
+// change, so certificates can be rotated without restarting the server.
+type Certificates struct {
+	files []*keyPair // only used by Add and Reload, which aren't called concurrently
+
+	sync.RWMutex
+	certs  []*tls.Certificate
+	byName map[string]*tls.Certificate
+}
+
+// keyPair is a certificate and key file and the state of these files when they were last loaded.
+type keyPair struct {
+	certPath, keyPath string
+	cert, key         fileState
+}
+
+type fileState struct {
+	mtime time.Time
+	size  int64
+}
+
+// NewCertificates returns a new and empty Certificates.
+func NewCertificates() *Certificates {
+	return &Certificates{byName: make(map[string]*tls.Certificate)}
+}
+
+// Add loads the certificate and key from the PEM files certPath and keyPath. The first certificate that
+// is added is used for clients that don't ask for a name that is in any of the certificates.
+func (c *Certificates) Add(certPath, keyPath string) error {
+	kp := &keyPair{certPath: certPath, keyPath: keyPath}
+	cert, err := kp.load()
+	if err != nil {
+		return err
+	}
+	c.files = append(c.files, kp)
+
+	c.Lock()
+	defer c.Unlock()
+	c.certs = append(c.certs, cert)
+	c.index()
+	return nil
+}
+
+// Reload loads the certificates whose files changed since they were last loaded. If a certificate
+// can't be loaded, for instance because only one of the files has been updated yet, the previous one
+// is kept and an error is returned. Reload reports if any certificate was replaced.
+func (c *Certificates) Reload() (bool, error) {
+	changed := make(map[int]*tls.Certificate)
+	var errs []string
+	for i, kp := range c.files {
+		if !kp.changed() {
+			continue
+		}
+		cert, err := kp.load()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		changed[i] = cert
+	}
+
+	if len(changed) > 0 {
+		c.Lock()
+		for i, cert := range changed {
+			c.certs[i] = cert
+		}
+		c.index()
+		c.Unlock()
+	}
+
+	if len(errs) > 0 {
+		return len(changed) > 0, errors.New(strings.Join(errs, "; "))
+	}
+	return len(changed) > 0, nil
+}
+
+// GetCertificate returns the certificate for the server name in hello. It has the signature of
+// GetCertificate in tls.Config.
+func (c *Certificates) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.certs) == 0 {
+		return nil, errors.New("no certificates configured")
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := c.byName[name]; ok {
+		return cert, nil
+	}
+	// Try a wildcard certificate for the name.
+	if i := strings.Index(name, "."); i > 0 {
+		if cert, ok := c.byName["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+	return c.certs[0], nil
+}
+
+// index rebuilds byName from the names in the certificates, the first certificate with a name wins.
+// The caller must hold the lock.
+func (c *Certificates) index() {
+	c.byName = make(map[string]*tls.Certificate)
+	for _, cert := range c.certs {
+		for _, name := range names(cert) {
+			name = strings.ToLower(name)
+			if _, ok := c.byName[name]; !ok {
+				c.byName[name] = cert
+			}
+		}
+	}
+}
+
+// names returns the DNS names of cert, or its common name if it doesn't have any.
+func names(cert *tls.Certificate) []string {
+	if cert.Leaf == nil {
+		return nil
+	}
+	if len(cert.Leaf.DNSNames) > 0 {
+		return cert.Leaf.DNSNames
+	}
+	if cert.Leaf.Subject.CommonName != "" {
+		return []string{cert.Leaf.Subject.CommonName}
+	}
+	return nil
+}
+
+func (kp *keyPair) load() (*tls.Certificate, error) {
+	cs, err := stat(kp.certPath)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := stat(kp.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(kp.certPath, kp.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS cert: %s", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("could not parse TLS cert: %s", err)
+		}
+	}
+	kp.cert, kp.key = cs, ks
+	return &cert, nil
+}
+
+// changed reports if the certificate or key file changed since they were loaded. Files that can't be
+// read are considered to be changed, so the error surfaces when loading them.
+func (kp *keyPair) changed() bool {
+	cs, err := stat(kp.certPath)
+	if err != nil {
+		return true
+	}
+	ks, err := stat(kp.keyPath)
+	if err != nil {
+		return true
+	}
+	return cs != kp.cert || ks != kp.key
+}
+
+func stat(path string) (fileState, error) {
+	// Stat follows symlinks, which is how certificates in Kubernetes secrets are updated.
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, fmt.Errorf("could not load TLS cert: %s", err)
+	}
+	return fileState{mtime: fi.ModTime(), size: fi.Size()}, nil
+}
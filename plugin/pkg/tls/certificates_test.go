@@ -0,0 +1,133 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for names and its key to dir, and returns their paths.
+func writeCert(t *testing.T, dir, file string, names ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %s", err)
+	}
+
+	cert := filepath.Join(dir, file+".pem")
+	if err := ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %s", err)
+	}
+	keyPath := filepath.Join(dir, file+"-key.pem")
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %s", err)
+	}
+	return cert, keyPath
+}
+
+// serverName returns the first name in the certificate that is picked for sni.
+func serverName(t *testing.T, c *Certificates, sni string) string {
+	cert, err := c.GetCertificate(&tls.ClientHelloInfo{ServerName: sni})
+	if err != nil {
+		t.Fatalf("Failed to get certificate for %q: %s", sni, err)
+	}
+	return cert.Leaf.DNSNames[0]
+}
+
+func TestCertificatesSNI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewCertificates()
+	if _, err := c.GetCertificate(&tls.ClientHelloInfo{}); err == nil {
+		t.Errorf("Expected error without certificates")
+	}
+
+	c.Add(writeCert(t, dir, "a", "a.example.org"))
+	c.Add(writeCert(t, dir, "b", "*.example.net", "b.example.org"))
+
+	tests := []struct {
+		sni      string
+		expected string
+	}{
+		{"a.example.org", "a.example.org"},
+		{"B.example.org.", "*.example.net"},
+		{"www.example.net", "*.example.net"},
+		{"example.net", "a.example.org"},
+		{"", "a.example.org"},
+	}
+	for i, tc := range tests {
+		if x := serverName(t, c, tc.sni); x != tc.expected {
+			t.Errorf("Test %d: expected certificate for %s, got %s", i, tc.expected, x)
+		}
+	}
+
+	if err := c.Add(filepath.Join(dir, "c.pem"), filepath.Join(dir, "c-key.pem")); err == nil {
+		t.Errorf("Expected error for missing files")
+	}
+}
+
+func TestCertificatesReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewCertificates()
+	cert, key := writeCert(t, dir, "a", "a.example.org")
+	if err := c.Add(cert, key); err != nil {
+		t.Fatalf("Failed to add certificate: %s", err)
+	}
+
+	if changed, err := c.Reload(); changed || err != nil {
+		t.Errorf("Expected no change, got %t, %v", changed, err)
+	}
+
+	// Make sure the modification time changes, even with a coarse clock.
+	writeCert(t, dir, "a", "new.example.org")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(cert, future, future)
+	if changed, err := c.Reload(); !changed || err != nil {
+		t.Errorf("Expected certificate to be reloaded, got %t, %v", changed, err)
+	}
+	if x := serverName(t, c, "new.example.org"); x != "new.example.org" {
+		t.Errorf("Expected new certificate, got %s", x)
+	}
+
+	// A broken key keeps the current certificate.
+	ioutil.WriteFile(key, []byte("broken"), 0600)
+	if changed, err := c.Reload(); changed || err == nil {
+		t.Errorf("Expected error and no change, got %t, %v", changed, err)
+	}
+	if x := serverName(t, c, "new.example.org"); x != "new.example.org" {
+		t.Errorf("Expected certificate to be kept, got %s", x)
+	}
+}
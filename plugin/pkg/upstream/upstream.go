@@ -30,6 +30,7 @@ func (u *Upstream) Lookup(ctx context.Context, state request.Request, name strin
 
 	nw := nonwriter.New(state.W)
 
+	ctx = context.WithValue(ctx, dnsserver.InternalKey{}, true)
 	server.ServeDNS(ctx, nw, req)
 
 	return nw.Msg, nil
@@ -0,0 +1,11 @@
+// Package proxyproto enables the PROXY protocol on the listeners of a server.
+package proxyproto
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("proxyproto", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
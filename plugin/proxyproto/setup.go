@@ -0,0 +1,87 @@
+package proxyproto
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.ProxyProtocol != nil {
+		return plugin.Error("proxyproto", c.Errf("PROXY protocol already configured for this server instance"))
+	}
+
+	pc, err := parse(c)
+	if err != nil {
+		return plugin.Error("proxyproto", err)
+	}
+	config.ProxyProtocol = pc
+	return nil
+}
+
+func parse(c *caddy.Controller) (*proxyproto.Config, error) {
+	pc := &proxyproto.Config{Timeout: defaultTimeout}
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return nil, c.ArgErr()
+		}
+		for _, a := range args {
+			n, err := parseNet(a)
+			if err != nil {
+				return nil, err
+			}
+			pc.Allow = append(pc.Allow, n)
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "timeout":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				if d <= 0 {
+					return nil, fmt.Errorf("timeout must be positive: %s", d)
+				}
+				pc.Timeout = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return pc, nil
+}
+
+// parseNet parses s as a CIDR, a plain IP address is taken as a network with just that address.
+func parseNet(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("not a valid IP address or CIDR: %s", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid IP address or CIDR: %s", s)
+	}
+	return n, nil
+}
+
+const defaultTimeout = 5 * time.Second
@@ -0,0 +1,54 @@
+package proxyproto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config   string
+		expected []string
+		timeout  time.Duration
+		failing  bool
+	}{
+		{`proxyproto 10.0.0.0/8`, []string{"10.0.0.0/8"}, defaultTimeout, false},
+		{`proxyproto 10.0.0.1 2001:db8::/32`, []string{"10.0.0.1/32", "2001:db8::/32"}, defaultTimeout, false},
+		{"proxyproto 10.0.0.0/8 {\ntimeout 2s\n}", []string{"10.0.0.0/8"}, 2 * time.Second, false},
+		{`proxyproto`, nil, 0, true},
+		{`proxyproto 10.0.0.0/33`, nil, 0, true},
+		{`proxyproto example.org`, nil, 0, true},
+		{"proxyproto 10.0.0.0/8 {\ntimeout\n}", nil, 0, true},
+		{"proxyproto 10.0.0.0/8 {\ntimeout -1s\n}", nil, 0, true},
+		{"proxyproto 10.0.0.0/8 {\nblah\n}", nil, 0, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not", i)
+		}
+		pc := dnsserver.GetConfig(c).ProxyProtocol
+		if len(pc.Allow) != len(test.expected) {
+			t.Errorf("Test %d: expected %d networks, got %d", i, len(test.expected), len(pc.Allow))
+			continue
+		}
+		for j, v := range test.expected {
+			if got := pc.Allow[j].String(); got != v {
+				t.Errorf("Test %d: expected network %s, got %s", i, v, got)
+			}
+		}
+		if pc.Timeout != test.timeout {
+			t.Errorf("Test %d: expected timeout %s, got %s", i, test.timeout, pc.Timeout)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package reuseport sets the number of UDP sockets a server opens on its address.
+package reuseport
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("reuseport", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
@@ -0,0 +1,48 @@
+package reuseport
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.ReusePort > 0 {
+		return plugin.Error("reuseport", c.Errf("reuseport already configured for this server instance"))
+	}
+
+	n, err := parse(c)
+	if err != nil {
+		return plugin.Error("reuseport", err)
+	}
+	config.ReusePort = n
+	return nil
+}
+
+func parse(c *caddy.Controller) (n int, err error) {
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return 0, c.ArgErr()
+		}
+		n, err = strconv.Atoi(args[0])
+		if err != nil {
+			return 0, err
+		}
+		if n < 1 {
+			return 0, fmt.Errorf("number of sockets must be at least 1: %d", n)
+		}
+		if n > max {
+			return 0, fmt.Errorf("number of sockets can't be more than %d: %d", max, n)
+		}
+	}
+	return n, nil
+}
+
+const max = 1024 // Maximum number of sockets.
@@ -0,0 +1,40 @@
+package reuseport
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config   string
+		expected int
+		failing  bool
+	}{
+		{`reuseport 1`, 1, false},
+		{`reuseport 32`, 32, false},
+		{`reuseport`, 0, true},
+		{`reuseport 0`, 0, true},
+		{`reuseport 2048`, 0, true},
+		{`reuseport many`, 0, true},
+		{`reuseport 4 8`, 0, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not", i)
+		}
+		if n := dnsserver.GetConfig(c).ReusePort; n != test.expected {
+			t.Errorf("Test %d: expected %d, got %d", i, test.expected, n)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package route53
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// weights holds the weighted record sets of a hosted zone, keyed by setKey. For every query one of the
+// sets is picked by weight.
+type weights map[string][]weightedSet
+
+type weightedSet struct {
+	weight  int64
+	records map[string]bool // the records of the set, in presentation format
+}
+
+// pick returns the records in rrs that are not weighted, and of the weighted ones only those from a
+// single set per name and type, picked by weight.
+func (w weights) pick(rrs []dns.RR) []dns.RR {
+	if len(w) == 0 {
+		return rrs
+	}
+
+	chosen := map[string]int{}
+	ret := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		key := setKey(rr.Header().Name, dns.TypeToString[rr.Header().Rrtype])
+		sets, ok := w[key]
+		if !ok {
+			ret = append(ret, rr)
+			continue
+		}
+		i, ok := chosen[key]
+		if !ok {
+			i = pickWeighted(sets)
+			chosen[key] = i
+		}
+		if sets[i].records[rr.String()] {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// pickWeighted returns the index of the set picked at random by weight. Sets with a weight of 0 are
+// only picked when all sets have a weight of 0.
+func pickWeighted(sets []weightedSet) int {
+	total := int64(0)
+	for _, s := range sets {
+		total += s.weight
+	}
+	if total == 0 {
+		return rand.Intn(len(sets))
+	}
+	r := rand.Int63n(total)
+	for i, s := range sets {
+		if r < s.weight {
+			return i
+		}
+		r -= s.weight
+	}
+	return len(sets) - 1
+}
+
+// setKey returns the key for the record sets with name and type typ.
+func setKey(name, typ string) string {
+	return strings.ToLower(dns.Fqdn(name)) + " " + typ
+}
+
+// healthChecks returns the status of the health checks used by the record sets in sets, keyed by
+// health check ID. When the status of a health check can't be retrieved it is considered healthy.
+func (h *Route53) healthChecks(ctx context.Context, sets []*route53.ResourceRecordSet) map[string]bool {
+	status := map[string]bool{}
+	for _, rrs := range sets {
+		id := aws.StringValue(rrs.HealthCheckId)
+		if id == "" {
+			continue
+		}
+		if _, ok := status[id]; ok {
+			continue
+		}
+		out, err := h.client.GetHealthCheckStatusWithContext(ctx, &route53.GetHealthCheckStatusInput{HealthCheckId: aws.String(id)})
+		if err != nil {
+			log.Warningf("Failed to get status of health check %s: %v", id, err)
+			status[id] = true
+			continue
+		}
+		status[id] = healthy(out.HealthCheckObservations)
+	}
+	return status
+}
+
+// healthy returns true if more than half of the Route53 health checkers consider the endpoint healthy.
+func healthy(obs []*route53.HealthCheckObservation) bool {
+	if len(obs) == 0 {
+		return true
+	}
+	ok := 0
+	for _, o := range obs {
+		if o.StatusReport != nil && strings.HasPrefix(aws.StringValue(o.StatusReport.Status), "Success") {
+			ok++
+		}
+	}
+	return ok*2 > len(obs)
+}
+
+// choose returns the record sets from group, record sets with the same name and type that have a routing
+// policy, that should be served. Like Route53 does, when all record sets are unhealthy they are all
+// considered healthy.
+func (h *Route53) choose(group []*route53.ResourceRecordSet, status map[string]bool) []*route53.ResourceRecordSet {
+	var candidates []*route53.ResourceRecordSet
+	for _, rrs := range group {
+		if ok, found := status[aws.StringValue(rrs.HealthCheckId)]; !found || ok {
+			candidates = append(candidates, rrs)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = group
+	}
+
+	switch {
+	case group[0].Failover != nil:
+		for _, rrs := range candidates {
+			if aws.StringValue(rrs.Failover) == route53.ResourceRecordSetFailoverPrimary {
+				return []*route53.ResourceRecordSet{rrs}
+			}
+		}
+		return candidates[:1]
+
+	case group[0].Region != nil:
+		for _, rrs := range candidates {
+			if aws.StringValue(rrs.Region) == h.region {
+				return []*route53.ResourceRecordSet{rrs}
+			}
+		}
+		// Not in any of the regions, use the first one so all instances of CoreDNS agree.
+		sort.Slice(candidates, func(i, j int) bool {
+			return aws.StringValue(candidates[i].Region) < aws.StringValue(candidates[j].Region)
+		})
+		return candidates[:1]
+	}
+
+	// Weighted record sets are picked per query, for other policies all healthy record sets are served.
+	return candidates
+}
+
+// newZone returns a zone named zName that holds the records from sets, the record sets of the hosted zone
+// with id hostedZoneID. Record sets with a routing policy are resolved using the health checks, alias records
+// are resolved to the records of their target.
+func (h *Route53) newZone(ctx context.Context, zName, hostedZoneID string, sets []*route53.ResourceRecordSet) (*file.Zone, weights) {
+	var (
+		serve    []*route53.ResourceRecordSet
+		keys     []string
+		policies = map[string][]*route53.ResourceRecordSet{}
+	)
+	for _, rrs := range sets {
+		if rrs.SetIdentifier == nil {
+			serve = append(serve, rrs)
+			continue
+		}
+		name, err := maybeUnescape(aws.StringValue(rrs.Name))
+		if err != nil {
+			log.Warningf("Failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
+			continue
+		}
+		key := setKey(name, aws.StringValue(rrs.Type))
+		if _, ok := policies[key]; !ok {
+			keys = append(keys, key)
+		}
+		policies[key] = append(policies[key], rrs)
+	}
+
+	status := h.healthChecks(ctx, sets)
+	var weighted []*route53.ResourceRecordSet
+	for _, key := range keys {
+		chosen := h.choose(policies[key], status)
+		if chosen[0].Weight != nil && len(chosen) > 1 {
+			weighted = append(weighted, chosen...)
+		}
+		serve = append(serve, chosen...)
+	}
+
+	z := file.NewZone(zName, "")
+	z.Upstream = h.upstream
+
+	records := make(map[*route53.ResourceRecordSet][]dns.RR, len(serve))
+	byKey := map[string][]dns.RR{}
+	for _, rrs := range serve {
+		if rrs.AliasTarget != nil {
+			continue
+		}
+		rs, err := recordsFromRRS(rrs)
+		if err != nil {
+			// Maybe unsupported record type. Log and carry on.
+			log.Warningf("Failed to process resource record set: %v", err)
+			continue
+		}
+		records[rrs] = rs
+		for _, r := range rs {
+			key := setKey(r.Header().Name, dns.TypeToString[r.Header().Rrtype])
+			byKey[key] = append(byKey[key], r)
+		}
+	}
+	for _, rrs := range serve {
+		if rrs.AliasTarget == nil {
+			continue
+		}
+		rs, err := h.aliasRecords(ctx, rrs, hostedZoneID, byKey)
+		if err != nil {
+			log.Warningf("Failed to resolve alias record set: %v", err)
+			continue
+		}
+		records[rrs] = rs
+	}
+
+	for _, rs := range records {
+		for _, r := range rs {
+			z.Insert(r)
+		}
+	}
+
+	w := weights{}
+	for _, rrs := range weighted {
+		rs, ok := records[rrs]
+		if !ok || len(rs) == 0 {
+			continue
+		}
+		set := weightedSet{weight: aws.Int64Value(rrs.Weight), records: make(map[string]bool, len(rs))}
+		for _, r := range rs {
+			set.records[r.String()] = true
+		}
+		key := setKey(rs[0].Header().Name, dns.TypeToString[rs[0].Header().Rrtype])
+		w[key] = append(w[key], set)
+	}
+	return z, w
+}
+
+// aliasRecords returns the records for the alias record set rrs. When the target is in the same hosted
+// zone its records are copied from byKey, other targets (load balancers, CloudFront distributions, etc.)
+// are resolved, which only works for A and AAAA record sets.
+func (h *Route53) aliasRecords(ctx context.Context, rrs *route53.ResourceRecordSet, hostedZoneID string, byKey map[string][]dns.RR) ([]dns.RR, error) {
+	name, err := maybeUnescape(aws.StringValue(rrs.Name))
+	if err != nil {
+		return nil, err
+	}
+	name = dns.Fqdn(name)
+	target, err := maybeUnescape(aws.StringValue(rrs.AliasTarget.DNSName))
+	if err != nil {
+		return nil, err
+	}
+	typ := aws.StringValue(rrs.Type)
+
+	var rs []dns.RR
+	if strings.TrimPrefix(aws.StringValue(rrs.AliasTarget.HostedZoneId), "/hostedzone/") == hostedZoneID {
+		for _, r := range byKey[setKey(target, typ)] {
+			r = dns.Copy(r)
+			r.Header().Name = name
+			rs = append(rs, r)
+		}
+		return rs, nil
+	}
+
+	if typ != "A" && typ != "AAAA" {
+		return nil, nil
+	}
+	addrs, err := h.lookupIPAddr(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: aliasTTL}
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			if typ == "A" {
+				hdr.Rrtype = dns.TypeA
+				rs = append(rs, &dns.A{Hdr: hdr, A: ip4})
+			}
+			continue
+		}
+		if typ == "AAAA" {
+			hdr.Rrtype = dns.TypeAAAA
+			rs = append(rs, &dns.AAAA{Hdr: hdr, AAAA: addr.IP})
+		}
+	}
+	return rs, nil
+}
+
+// visible returns true if the hosted zone z may be used to answer queries from ip.
+func (h *Route53) visible(z *zone, ip net.IP) bool {
+	if !z.private || len(h.private) == 0 {
+		return true
+	}
+	for _, n := range h.private {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasTTL is the TTL of the records we return for alias records to targets outside the hosted zone.
+const aliasTTL = 60
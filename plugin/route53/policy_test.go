@@ -0,0 +1,222 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// fakePolicyRoute53 serves a public (POLICY) and a private (PRIVATE) hosted zone for example.net.
+type fakePolicyRoute53 struct {
+	fakeRoute53
+}
+
+func (fakePolicyRoute53) ListHostedZonesByNameWithContext(_ aws.Context, in *route53.ListHostedZonesByNameInput, _ ...request.Option) (*route53.ListHostedZonesByNameOutput, error) {
+	id := aws.StringValue(in.HostedZoneId)
+	return &route53.ListHostedZonesByNameOutput{
+		HostedZones: []*route53.HostedZone{
+			{
+				Id:     aws.String("/hostedzone/" + id),
+				Name:   in.DNSName,
+				Config: &route53.HostedZoneConfig{PrivateZone: aws.Bool(id == "PRIVATE")},
+			},
+		},
+	}, nil
+}
+
+func (fakePolicyRoute53) GetHealthCheckStatusWithContext(_ aws.Context, in *route53.GetHealthCheckStatusInput, _ ...request.Option) (*route53.GetHealthCheckStatusOutput, error) {
+	status := "Success: HTTP Status Code 200, OK"
+	if aws.StringValue(in.HealthCheckId) == "hc-down" {
+		status = "Failure: Connection timed out."
+	}
+	obs := []*route53.HealthCheckObservation{}
+	for i := 0; i < 3; i++ {
+		obs = append(obs, &route53.HealthCheckObservation{StatusReport: &route53.StatusReport{Status: aws.String(status)}})
+	}
+	return &route53.GetHealthCheckStatusOutput{HealthCheckObservations: obs}, nil
+}
+
+func rrs(name, typ, value string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String(typ),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+	}
+}
+
+func withPolicy(s *route53.ResourceRecordSet, id, healthCheck string, f func(*route53.ResourceRecordSet)) *route53.ResourceRecordSet {
+	s.SetIdentifier = aws.String(id)
+	if healthCheck != "" {
+		s.HealthCheckId = aws.String(healthCheck)
+	}
+	f(s)
+	return s
+}
+
+func alias(name, typ, target, hostedZoneID string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:        aws.String(name),
+		Type:        aws.String(typ),
+		AliasTarget: &route53.AliasTarget{DNSName: aws.String(target), HostedZoneId: aws.String(hostedZoneID), EvaluateTargetHealth: aws.Bool(false)},
+	}
+}
+
+func failover(f string) func(*route53.ResourceRecordSet) {
+	return func(s *route53.ResourceRecordSet) { s.Failover = aws.String(f) }
+}
+
+func latency(region string) func(*route53.ResourceRecordSet) {
+	return func(s *route53.ResourceRecordSet) { s.Region = aws.String(region) }
+}
+
+func weight(w int64) func(*route53.ResourceRecordSet) {
+	return func(s *route53.ResourceRecordSet) { s.Weight = aws.Int64(w) }
+}
+
+func (fakePolicyRoute53) ListResourceRecordSetsPagesWithContext(_ aws.Context, in *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, _ ...request.Option) error {
+	sets := map[string][]*route53.ResourceRecordSet{
+		"POLICY": {
+			rrs("example.net.", "SOA", "ns-1536.awsdns-00.co.uk. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+			// Primary is down.
+			withPolicy(rrs("www.example.net.", "A", "10.0.0.1"), "primary", "hc-down", failover("PRIMARY")),
+			withPolicy(rrs("www.example.net.", "A", "10.0.0.2"), "secondary", "", failover("SECONDARY")),
+			// Primary is up.
+			withPolicy(rrs("up.example.net.", "A", "10.0.1.1"), "primary", "hc-up", failover("PRIMARY")),
+			withPolicy(rrs("up.example.net.", "A", "10.0.1.2"), "secondary", "", failover("SECONDARY")),
+			// Latency.
+			withPolicy(rrs("lat.example.net.", "A", "10.0.2.1"), "us", "", latency("us-east-1")),
+			withPolicy(rrs("lat.example.net.", "A", "10.0.2.2"), "eu", "", latency("eu-west-1")),
+			// Weighted, only one set can be picked.
+			withPolicy(rrs("w.example.net.", "A", "10.0.3.1"), "heavy", "", weight(100)),
+			withPolicy(rrs("w.example.net.", "A", "10.0.3.2"), "zero", "", weight(0)),
+			withPolicy(rrs("w.example.net.", "A", "10.0.3.3"), "down", "hc-down", weight(50)),
+			// Weighted, evenly.
+			withPolicy(rrs("even.example.net.", "A", "10.0.4.1"), "one", "", weight(1)),
+			withPolicy(rrs("even.example.net.", "A", "10.0.4.2"), "two", "", weight(1)),
+			// Aliases.
+			alias("example.net.", "A", "www.example.net.", "POLICY"),
+			alias("elb.example.net.", "A", "lb-1234.eu-west-1.elb.amazonaws.com.", "Z32O12XQLNTSW2"),
+			alias("elb.example.net.", "AAAA", "lb-1234.eu-west-1.elb.amazonaws.com.", "Z32O12XQLNTSW2"),
+		},
+		"PRIVATE": {
+			rrs("example.net.", "SOA", "ns-1536.awsdns-00.co.uk. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+			rrs("www.example.net.", "A", "10.9.9.9"),
+		},
+	}
+
+	if ok := fn(&route53.ListResourceRecordSetsOutput{ResourceRecordSets: sets[aws.StringValue(in.HostedZoneId)]}, true); !ok {
+		return errors.New("paging function return false")
+	}
+	return nil
+}
+
+func newPolicyRoute53(t *testing.T, private string) *Route53 {
+	ctx := context.Background()
+	r, err := New(ctx, fakePolicyRoute53{}, map[string][]string{"example.net.": {"PRIVATE", "POLICY"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Route53: %v", err)
+	}
+	r.region = "eu-west-1"
+	_, n, _ := net.ParseCIDR(private)
+	r.private = []*net.IPNet{n}
+	r.lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if host != "lb-1234.eu-west-1.elb.amazonaws.com." {
+			return nil, errors.New("no such host")
+		}
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}}, nil
+	}
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Failed to initialize Route53: %v", err)
+	}
+	return r
+}
+
+func TestRoute53Policies(t *testing.T) {
+	// test.ResponseWriter uses 10.240.0.1, so we don't see the private hosted zone.
+	r := newPolicyRoute53(t, "192.168.0.0/16")
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.net. 300 IN A 10.0.0.2")},
+		},
+		{
+			Qname: "up.example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("up.example.net. 300 IN A 10.0.1.1")},
+		},
+		{
+			Qname: "lat.example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("lat.example.net. 300 IN A 10.0.2.2")},
+		},
+		{
+			Qname: "w.example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("w.example.net. 300 IN A 10.0.3.1")},
+		},
+		{
+			Qname: "example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("example.net. 300 IN A 10.0.0.2")},
+		},
+		{
+			Qname: "elb.example.net.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("elb.example.net. 60 IN A 192.0.2.1")},
+		},
+		{
+			Qname: "elb.example.net.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("elb.example.net. 60 IN AAAA 2001:db8::1")},
+		},
+	}
+
+	for i, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(context.Background(), rec, tc.Msg()); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < 100; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("even.example.net.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		r.ServeDNS(context.Background(), rec, m)
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Expected 1 weighted answer, got %d", len(rec.Msg.Answer))
+		}
+		seen[rec.Msg.Answer[0].(*dns.A).A.String()]++
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both weighted record sets to be picked, got %v", seen)
+	}
+}
+
+func TestRoute53Private(t *testing.T) {
+	r := newPolicyRoute53(t, "10.240.0.0/16")
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.net.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.Background(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tc := test.Case{
+		Qname: "www.example.net.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("www.example.net. 300 IN A 10.9.9.9")},
+	}
+	if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+		t.Error(err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,15 +32,22 @@ type Route53 struct {
 	zoneNames []string
 	client    route53iface.Route53API
 	upstream  *upstream.Upstream
+	refresh   time.Duration
+	region    string       // AWS region we run in, used for latency record sets
+	private   []*net.IPNet // when set, only clients in these subnets see private hosted zones
+
+	lookupIPAddr func(context.Context, string) ([]net.IPAddr, error) // used to resolve alias targets
 
 	zMu   sync.RWMutex
 	zones zones
 }
 
 type zone struct {
-	id  string
-	z   *file.Zone
-	dns string
+	id       string
+	z        *file.Zone
+	dns      string
+	private  bool
+	weighted weights
 }
 
 type zones map[string][]*zone
@@ -54,7 +62,7 @@ func New(ctx context.Context, c route53iface.Route53API, keys map[string][]strin
 	zoneNames := make([]string, 0, len(keys))
 	for dns, hostedZoneIDs := range keys {
 		for _, hostedZoneID := range hostedZoneIDs {
-			_, err := c.ListHostedZonesByNameWithContext(ctx, &route53.ListHostedZonesByNameInput{
+			out, err := c.ListHostedZonesByNameWithContext(ctx, &route53.ListHostedZonesByNameInput{
 				DNSName:      aws.String(dns),
 				HostedZoneId: aws.String(hostedZoneID),
 			})
@@ -64,17 +72,32 @@ func New(ctx context.Context, c route53iface.Route53API, keys map[string][]strin
 			if _, ok := zones[dns]; !ok {
 				zoneNames = append(zoneNames, dns)
 			}
-			zones[dns] = append(zones[dns], &zone{id: hostedZoneID, dns: dns, z: file.NewZone(dns, "")})
+			zones[dns] = append(zones[dns], &zone{id: hostedZoneID, dns: dns, z: file.NewZone(dns, ""), private: isPrivate(out, hostedZoneID)})
 		}
 	}
 	return &Route53{
-		client:    c,
-		zoneNames: zoneNames,
-		zones:     zones,
-		upstream:  up,
+		client:       c,
+		zoneNames:    zoneNames,
+		zones:        zones,
+		upstream:     up,
+		refresh:      defaultRefresh,
+		lookupIPAddr: net.DefaultResolver.LookupIPAddr,
 	}, nil
 }
 
+// isPrivate returns true if the hosted zone with id is a private hosted zone.
+func isPrivate(out *route53.ListHostedZonesByNameOutput, id string) bool {
+	if out == nil {
+		return false
+	}
+	for _, hz := range out.HostedZones {
+		if strings.TrimPrefix(aws.StringValue(hz.Id), "/hostedzone/") == id && hz.Config != nil {
+			return aws.BoolValue(hz.Config.PrivateZone)
+		}
+	}
+	return false
+}
+
 // Run executes first update, spins up an update forever-loop.
 // Returns error if first update fails.
 func (h *Route53) Run(ctx context.Context) error {
@@ -87,7 +110,7 @@ func (h *Route53) Run(ctx context.Context) error {
 			case <-ctx.Done():
 				log.Infof("Breaking out of Route53 update loop: %v", ctx.Err())
 				return
-			case <-time.After(1 * time.Minute):
+			case <-time.After(h.refresh):
 				if err := h.updateZones(ctx); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
 					log.Errorf("Failed to update zones: %v", err)
 				}
@@ -111,14 +134,24 @@ func (h *Route53) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		return dns.RcodeServerFailure, nil
 	}
 
+	ip := net.ParseIP(state.IP())
+	visible := 0
+
 	m := new(dns.Msg)
 	m.SetReply(r)
 	m.Authoritative = true
 	var result file.Result
 	for _, hostedZone := range z {
+		if !h.visible(hostedZone, ip) {
+			continue
+		}
+		visible++
+
 		h.zMu.RLock()
 		m.Answer, m.Ns, m.Extra, result = hostedZone.z.Lookup(ctx, state, qname)
+		weighted := hostedZone.weighted
 		h.zMu.RUnlock()
+		m.Answer = weighted.pick(m.Answer)
 
 		// Take the answer if it's non-empty OR if there is another
 		// record type exists for this name (NODATA).
@@ -127,6 +160,11 @@ func (h *Route53) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		}
 	}
 
+	if visible == 0 {
+		// Only private hosted zones this client can't see.
+		return plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+	}
+
 	if len(m.Answer) == 0 && result != file.NoData && h.Fall.Through(qname) {
 		return plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
 	}
@@ -205,28 +243,30 @@ func maybeUnescape(s string) (string, error) {
 	}
 }
 
-func updateZoneFromRRS(rrs *route53.ResourceRecordSet, z *file.Zone) error {
+// recordsFromRRS returns the records in the resource record set rrs.
+func recordsFromRRS(rrs *route53.ResourceRecordSet) ([]dns.RR, error) {
+	var rs []dns.RR
 	for _, rr := range rrs.ResourceRecords {
 
 		n, err := maybeUnescape(aws.StringValue(rrs.Name))
 		if err != nil {
-			return fmt.Errorf("failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
+			return nil, fmt.Errorf("failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
 		}
 		v, err := maybeUnescape(aws.StringValue(rr.Value))
 		if err != nil {
-			return fmt.Errorf("failed to unescape `%s' value: %v", aws.StringValue(rr.Value), err)
+			return nil, fmt.Errorf("failed to unescape `%s' value: %v", aws.StringValue(rr.Value), err)
 		}
 
 		// Assemble RFC 1035 conforming record to pass into dns scanner.
 		rfc1035 := fmt.Sprintf("%s %d IN %s %s", n, aws.Int64Value(rrs.TTL), aws.StringValue(rrs.Type), v)
 		r, err := dns.NewRR(rfc1035)
 		if err != nil {
-			return fmt.Errorf("failed to parse resource record: %v", err)
+			return nil, fmt.Errorf("failed to parse resource record: %v", err)
 		}
 
-		z.Insert(r)
+		rs = append(rs, r)
 	}
-	return nil
+	return rs, nil
 }
 
 // updateZones re-queries resource record sets for each zone and updates the
@@ -244,27 +284,23 @@ func (h *Route53) updateZones(ctx context.Context) error {
 			}()
 
 			for i, hostedZone := range z {
-				newZ := file.NewZone(zName, "")
-				newZ.Upstream = h.upstream
+				var sets []*route53.ResourceRecordSet
 				in := &route53.ListResourceRecordSetsInput{
 					HostedZoneId: aws.String(hostedZone.id),
 				}
 				err = h.client.ListResourceRecordSetsPagesWithContext(ctx, in,
 					func(out *route53.ListResourceRecordSetsOutput, last bool) bool {
-						for _, rrs := range out.ResourceRecordSets {
-							if err := updateZoneFromRRS(rrs, newZ); err != nil {
-								// Maybe unsupported record type. Log and carry on.
-								log.Warningf("Failed to process resource record set: %v", err)
-							}
-						}
+						sets = append(sets, out.ResourceRecordSets...)
 						return true
 					})
 				if err != nil {
 					err = fmt.Errorf("failed to list resource records for %v:%v from route53: %v", zName, hostedZone.id, err)
 					return
 				}
+				newZ, weighted := h.newZone(ctx, zName, hostedZone.id, sets)
 				h.zMu.Lock()
 				(*z[i]).z = newZ
+				(*z[i]).weighted = weighted
 				h.zMu.Unlock()
 			}
 
@@ -285,5 +321,7 @@ func (h *Route53) updateZones(ctx context.Context) error {
 	return nil
 }
 
+const defaultRefresh = 1 * time.Minute
+
 // Name implements plugin.Handler.Name.
 func (h *Route53) Name() string { return "route53" }
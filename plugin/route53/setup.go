@@ -2,7 +2,9 @@ package route53
 
 import (
 	"context"
+	"net"
 	"strings"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -47,6 +49,9 @@ func setup(c *caddy.Controller, f func(*credentials.Credentials) route53iface.Ro
 	sharedProvider := &credentials.SharedCredentialsProvider{}
 	var providers []credentials.Provider
 	var fall fall.F
+	var private []*net.IPNet
+	refresh := defaultRefresh
+	region := ""
 
 	up := upstream.New()
 	for c.Next() {
@@ -95,6 +100,35 @@ func setup(c *caddy.Controller, f func(*credentials.Credentials) route53iface.Ro
 				}
 			case "fallthrough":
 				fall.SetZonesFromArgs(c.RemainingArgs())
+			case "refresh":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return c.Errf("unable to parse refresh duration '%s': %v", c.Val(), err)
+				}
+				if d <= 0 {
+					return c.Errf("refresh interval must be greater than 0: %s", c.Val())
+				}
+				refresh = d
+			case "region":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				region = c.Val()
+			case "private":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return c.ArgErr()
+				}
+				for _, cidr := range args {
+					_, n, err := net.ParseCIDR(cidr)
+					if err != nil {
+						return c.Errf("invalid subnet '%s': %v", cidr, err)
+					}
+					private = append(private, n)
+				}
 			default:
 				return c.Errf("unknown property '%s'", c.Val())
 			}
@@ -103,15 +137,24 @@ func setup(c *caddy.Controller, f func(*credentials.Credentials) route53iface.Ro
 	providers = append(providers, &credentials.EnvProvider{}, sharedProvider)
 
 	client := f(credentials.NewChainCredentials(providers))
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 	h, err := New(ctx, client, keys, up)
 	if err != nil {
+		cancel()
 		return c.Errf("failed to create Route53 plugin: %v", err)
 	}
 	h.Fall = fall
+	h.refresh = refresh
+	h.region = region
+	h.private = private
 	if err := h.Run(ctx); err != nil {
+		cancel()
 		return c.Errf("failed to initialize Route53 plugin: %v", err)
 	}
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		h.Next = next
 		return h
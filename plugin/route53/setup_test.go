@@ -64,6 +64,29 @@ func TestSetupRoute53(t *testing.T) {
 
 		{`route53 example.org {
  		upstream 1.2.3.4
+	}`, true},
+		{`route53 example.org:12345678 {
+		refresh 90s
+		region eu-west-1
+		private 10.0.0.0/8 172.16.0.0/12
+	}`, false},
+		{`route53 example.org:12345678 {
+		refresh
+	}`, true},
+		{`route53 example.org:12345678 {
+		refresh 0s
+	}`, true},
+		{`route53 example.org:12345678 {
+		refresh forever
+	}`, true},
+		{`route53 example.org:12345678 {
+		region
+	}`, true},
+		{`route53 example.org:12345678 {
+		private
+	}`, true},
+		{`route53 example.org:12345678 {
+		private 10.0.0.1
 	}`, true},
 	}
 
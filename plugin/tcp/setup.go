@@ -0,0 +1,96 @@
+package tcp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.TCP != nil {
+		return plugin.Error("tcp", c.Errf("TCP already configured for this server instance"))
+	}
+
+	tc, err := parse(c)
+	if err != nil {
+		return plugin.Error("tcp", err)
+	}
+	config.TCP = tc
+	return nil
+}
+
+func parse(c *caddy.Controller) (*dnsserver.TCPConfig, error) {
+	tc := &dnsserver.TCPConfig{}
+	for c.Next() {
+		if len(c.RemainingArgs()) != 0 {
+			return nil, c.ArgErr()
+		}
+		for c.NextBlock() {
+			var err error
+			switch c.Val() {
+			case "max_connections":
+				tc.MaxConnections, err = parseInt(c, 1)
+			case "max_connections_per_client":
+				tc.MaxConnectionsPerClient, err = parseInt(c, 1)
+			case "max_queries":
+				tc.MaxQueries, err = parseInt(c, -1)
+				if tc.MaxQueries == 0 {
+					err = fmt.Errorf("max_queries can't be 0")
+				}
+			case "read_timeout":
+				tc.ReadTimeout, err = parseDuration(c)
+			case "idle_timeout":
+				tc.IdleTimeout, err = parseDuration(c)
+			case "write_timeout":
+				tc.WriteTimeout, err = parseDuration(c)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tc, nil
+}
+
+// parseInt parses the next argument as an integer of at least min.
+func parseInt(c *caddy.Controller, min int) (int, error) {
+	name := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if n < min {
+		return 0, fmt.Errorf("%s must be at least %d: %d", name, min, n)
+	}
+	return n, nil
+}
+
+// parseDuration parses the next argument as a positive duration.
+func parseDuration(c *caddy.Controller) (time.Duration, error) {
+	name := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive: %s", name, d)
+	}
+	return d, nil
+}
@@ -0,0 +1,45 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config   string
+		expected dnsserver.TCPConfig
+		failing  bool
+	}{
+		{`tcp`, dnsserver.TCPConfig{}, false},
+		{"tcp {\nmax_connections 1000\nmax_connections_per_client 10\n}", dnsserver.TCPConfig{MaxConnections: 1000, MaxConnectionsPerClient: 10}, false},
+		{"tcp {\nmax_queries -1\n}", dnsserver.TCPConfig{MaxQueries: -1}, false},
+		{"tcp {\nread_timeout 1s\nidle_timeout 10s\nwrite_timeout 500ms\n}", dnsserver.TCPConfig{ReadTimeout: time.Second, IdleTimeout: 10 * time.Second, WriteTimeout: 500 * time.Millisecond}, false},
+		{`tcp 10`, dnsserver.TCPConfig{}, true},
+		{"tcp {\nmax_connections 0\n}", dnsserver.TCPConfig{}, true},
+		{"tcp {\nmax_connections\n}", dnsserver.TCPConfig{}, true},
+		{"tcp {\nmax_queries 0\n}", dnsserver.TCPConfig{}, true},
+		{"tcp {\nread_timeout 0s\n}", dnsserver.TCPConfig{}, true},
+		{"tcp {\nidle_timeout soon\n}", dnsserver.TCPConfig{}, true},
+		{"tcp {\nblah\n}", dnsserver.TCPConfig{}, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not", i)
+		}
+		if tc := dnsserver.GetConfig(c).TCP; *tc != test.expected {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.expected, *tc)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package tcp sets the limits and timeouts for TCP and DNS-over-TLS connections.
+package tcp
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("tcp", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
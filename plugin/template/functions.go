@@ -0,0 +1,104 @@
+package template
+
+import (
+	"encoding/base32"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net"
+	"strings"
+	gotmpl "text/template"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+
+	"github.com/miekg/dns"
+)
+
+// funcMap holds the functions that can be used in templates, in addition to the ones
+// text/template provides.
+var funcMap = gotmpl.FuncMap{
+	"ipAdd":         ipAdd,
+	"ipFromReverse": dnsutil.ExtractAddressFromReverse,
+	"reverse":       reverse,
+	"replace":       replace,
+	"base32Encode":  base32Encode,
+	"base32Decode":  base32Decode,
+	"hash":          hash,
+}
+
+// Lookup resolves name with type typ via the upstream and returns the data of the records of
+// that type in the answer, i.e. the addresses for A and AAAA records.
+func (d templateData) Lookup(name, typ string) ([]string, error) {
+	if d.upstream == nil {
+		return nil, fmt.Errorf("no upstream configured")
+	}
+	qtype, ok := dns.StringToType[strings.ToUpper(typ)]
+	if !ok {
+		return nil, fmt.Errorf("invalid RR type %s", typ)
+	}
+
+	m, err := lookup(d.ctx, d.upstream, d.state, dns.Fqdn(name), qtype)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+	data := []string{}
+	for _, rr := range m.Answer {
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+		data = append(data, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return data, nil
+}
+
+// ipAdd adds n to the IP address ip.
+func ipAdd(ip string, n int) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	size := net.IPv6len
+	if v4 := addr.To4(); v4 != nil {
+		addr, size = v4, net.IPv4len
+	}
+
+	i := new(big.Int).SetBytes(addr)
+	i.Add(i, big.NewInt(int64(n)))
+	if i.Sign() < 0 || i.BitLen() > size*8 {
+		return "", fmt.Errorf("%s + %d overflows", ip, n)
+	}
+
+	buf := i.Bytes()
+	res := make(net.IP, size)
+	copy(res[size-len(buf):], buf)
+	return res.String(), nil
+}
+
+// reverse returns the reverse name (in in-addr.arpa. or ip6.arpa.) of the IP address ip.
+func reverse(ip string) (string, error) { return dns.ReverseAddr(ip) }
+
+// replace replaces all occurrences of old with new in s. The argument order allows
+// it to be used in pipelines: {{ .Name | replace "." "-" }}.
+func replace(old, new, s string) string { return strings.Replace(s, old, new, -1) }
+
+// b32 is the base32 encoding used: domain names are case insensitive and padding isn't valid in them.
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// base32Encode returns the lower case, unpadded, base32 encoding of s.
+func base32Encode(s string) string { return strings.ToLower(b32.EncodeToString([]byte(s))) }
+
+// base32Decode decodes the unpadded base32 string s, ignoring case.
+func base32Decode(s string) (string, error) {
+	b, err := b32.DecodeString(strings.ToUpper(strings.TrimRight(s, "=")))
+	return string(b), err
+}
+
+// hash returns the 32 bit FNV-1a hash of s in hex.
+func hash(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,140 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	gotmpl "text/template"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+type fakeUpstream map[string][]dns.RR
+
+func (f fakeUpstream) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, typ)
+	m.Answer = f[name]
+	return m, nil
+}
+
+func TestFunctions(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	o := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: []byte{192, 0, 2, 0}})
+	m.Extra = append(m.Extra, o)
+	// Go through the wire format, unpacking gives a 16 byte IPv4 address in the subnet option.
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Failed to pack message: %s", err)
+	}
+	m = new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("Failed to unpack message: %s", err)
+	}
+
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+	data := templateData{
+		Name:   "example.org.",
+		Remote: state.IP(),
+		ECS:    ecs(m),
+		ctx:    context.TODO(),
+		state:  state,
+		upstream: fakeUpstream{"www.example.org.": {
+			test.CNAME("www.example.org. 300 IN CNAME web.example.org."),
+			test.A("web.example.org. 300 IN A 192.0.2.1"),
+			test.A("web.example.org. 300 IN A 192.0.2.2"),
+		}},
+	}
+
+	tests := []struct {
+		tmpl      string
+		expected  string
+		shouldErr bool
+	}{
+		{`{{ .Remote }}`, "10.240.0.1", false},
+		{`{{ .ECS }}`, "192.0.2.0/24", false},
+		{`{{ ipAdd "10.0.0.1" 255 }}`, "10.0.1.0", false},
+		{`{{ ipAdd "10.0.0.1" -2 }}`, "9.255.255.255", false},
+		{`{{ ipAdd "2001:db8::ffff" 1 }}`, "2001:db8::1:0", false},
+		{`{{ ipAdd "255.255.255.255" 1 }}`, "", true},
+		{`{{ ipAdd "foo" 1 }}`, "", true},
+		{`{{ ipFromReverse "1.0.0.10.in-addr.arpa." | replace "." "-" }}`, "10-0-0-1", false},
+		{`{{ reverse "10.0.0.1" }}`, "1.0.0.10.in-addr.arpa.", false},
+		{`{{ base32Encode "coredns" }}`, "mnxxezlenzzq", false},
+		{`{{ base32Decode "MNXXEZLENZZQ" }}`, "coredns", false},
+		{`{{ base32Decode "1" }}`, "", true},
+		{`{{ hash "coredns" }}`, "99247189", false},
+		{`{{ index (.Lookup "www.example.org" "A") 1 }}`, "192.0.2.2", false},
+		{`{{ len (.Lookup "www.example.org" "AAAA") }}`, "0", false},
+		{`{{ .Lookup "www.example.org" "FOO" }}`, "", true},
+	}
+
+	for i, tc := range tests {
+		tmpl := gotmpl.Must(gotmpl.New("test").Funcs(funcMap).Parse(tc.tmpl))
+		buf := &bytes.Buffer{}
+		err := tmpl.Execute(buf, data)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got %q", i, buf.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, buf.String())
+		}
+	}
+}
+
+func TestLookupNoUpstream(t *testing.T) {
+	if _, err := (templateData{}).Lookup("example.org.", "A"); err == nil {
+		t.Error("Expected error without upstream")
+	}
+}
+
+// loopUpstream resolves names by sending them to h again, like the real upstream does.
+type loopUpstream struct {
+	h     *Handler
+	calls int
+}
+
+func (u *loopUpstream) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	u.calls++
+	m := new(dns.Msg)
+	m.SetQuestion(name, typ)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	u.h.ServeDNS(ctx, rec, m)
+	return rec.Msg, nil
+}
+
+func TestLookupLoop(t *testing.T) {
+	c := caddy.NewTestController("dns", `template IN TXT example.org {
+		answer "{{ .Name }} 60 IN TXT \"{{ .Lookup .Name \"TXT\" }}\""
+	}`)
+	h, err := templateParse(c)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %s", err)
+	}
+	u := &loopUpstream{h: &h}
+	h.Templates[0].upstream = u
+
+	m := new(dns.Msg)
+	m.SetQuestion("loop.example.org.", dns.TypeTXT)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := h.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if u.calls != maxLookupDepth {
+		t.Errorf("Expected %d lookups, got %d", maxLookupDepth, u.calls)
+	}
+}
@@ -148,6 +148,18 @@ func TestSetupParse(t *testing.T) {
 				}`,
 			false,
 		},
+		{
+			`template IN PTR in-addr.arpa {
+					answer "{{ .Name }} 60 IN PTR ip-{{ ipFromReverse .Name | replace ` + "`.` `-`" + ` }}.example."
+				}`,
+			false,
+		},
+		{
+			`template IN A example {
+					answer "{{ .Name }} 60 IN A {{ nosuchfunc .Remote }}"
+				}`,
+			true,
+		},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputFileRules)
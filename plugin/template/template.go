@@ -3,14 +3,15 @@ package template
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"regexp"
 	"strconv"
 	gotmpl "text/template"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/fall"
-	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -34,7 +35,12 @@ type template struct {
 	qclass     uint16
 	qtype      uint16
 	fall       fall.F
-	upstream   *upstream.Upstream
+	upstream   Upstream
+}
+
+// Upstream is used to resolve CNAME targets and the names looked up from within templates.
+type Upstream interface {
+	Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
 }
 
 type templateData struct {
@@ -47,6 +53,12 @@ type templateData struct {
 	Type     string
 	Message  *dns.Msg
 	Question *dns.Question
+	Remote   string // IP address of the client
+	ECS      string // EDNS0 client subnet of the query, empty when not present
+
+	ctx      context.Context
+	state    request.Request
+	upstream Upstream
 }
 
 // ServeDNS implements the plugin.Handler interface.
@@ -68,6 +80,8 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		}
 
 		templateMatchesCount.WithLabelValues(metrics.WithServer(ctx), data.Zone, data.Class, data.Type).Inc()
+		data.ctx = ctx
+		data.upstream = template.upstream
 
 		if template.rcode == dns.RcodeServerFailure {
 			return template.rcode, nil
@@ -85,8 +99,10 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			}
 			msg.Answer = append(msg.Answer, rr)
 			if template.upstream != nil && (state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA) && rr.Header().Rrtype == dns.TypeCNAME {
-				up, _ := template.upstream.Lookup(ctx, state, rr.(*dns.CNAME).Target, state.QType())
-				msg.Answer = append(msg.Answer, up.Answer...)
+				up, err := lookup(ctx, template.upstream, state, rr.(*dns.CNAME).Target, state.QType())
+				if err == nil && up != nil {
+					msg.Answer = append(msg.Answer, up.Answer...)
+				}
 			}
 		}
 		for _, additional := range template.additional {
@@ -114,6 +130,23 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 // Name implements the plugin.Handler interface.
 func (h Handler) Name() string { return "template" }
 
+// lookupDepthKey is the context key for the number of nested upstream lookups done by templates.
+type lookupDepthKey struct{}
+
+// maxLookupDepth limits the nesting of upstream lookups, a template that looks up a name it matches
+// itself would otherwise never stop.
+const maxLookupDepth = 8
+
+// lookup resolves name and typ via u, unless there are already maxLookupDepth lookups in progress
+// for this query.
+func lookup(ctx context.Context, u Upstream, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	depth, _ := ctx.Value(lookupDepthKey{}).(int)
+	if depth >= maxLookupDepth {
+		return nil, fmt.Errorf("too many nested lookups for %s", name)
+	}
+	return u.Lookup(context.WithValue(ctx, lookupDepthKey{}, depth+1), state, name, typ)
+}
+
 func executeRRTemplate(server, section string, template *gotmpl.Template, data templateData) (dns.RR, error) {
 	buffer := &bytes.Buffer{}
 	err := template.Execute(buffer, data)
@@ -155,6 +188,9 @@ func (t template) match(state request.Request, zone string) (templateData, bool,
 		data.Name = state.Name()
 		data.Question = &q
 		data.Message = state.Req
+		data.Remote = state.IP()
+		data.ECS = ecs(state.Req)
+		data.state = state
 		if q.Qclass != dns.ClassANY {
 			data.Class = dns.ClassToString[q.Qclass]
 		} else {
@@ -185,3 +221,16 @@ func (t template) match(state request.Request, zone string) (templateData, bool,
 
 	return data, false, t.fall.Through(state.Name())
 }
+
+// ecs returns the EDNS0 client subnet option of m as a CIDR string, or the empty string if m
+// doesn't have one.
+func ecs(m *dns.Msg) string {
+	e := edns.ClientSubnet(m)
+	if e == nil {
+		return ""
+	}
+	if n := edns.Subnet(e, e.SourceNetmask); n != nil {
+		return n.String()
+	}
+	return ""
+}
@@ -1,13 +1,20 @@
 package tls
 
 import (
+	ctls "crypto/tls"
+	"fmt"
+	"time"
+
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/tls"
 
 	"github.com/mholt/caddy"
 )
 
+var log = clog.NewWithPlugin("tls")
+
 func init() {
 	caddy.RegisterPlugin("tls", caddy.Plugin{
 		ServerType: "dns",
@@ -22,16 +29,128 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("tls", c.Errf("TLS already configured for this server instance"))
 	}
 
+	tc, certs, reload, err := parseTLS(c)
+	if err != nil {
+		return plugin.Error("tls", err)
+	}
+	config.TLSConfig = tc
+
+	stop := periodicReload(certs, reload)
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	return nil
+}
+
+func parseTLS(c *caddy.Controller) (*ctls.Config, *tls.Certificates, time.Duration, error) {
+	var (
+		tc     *ctls.Config
+		certs  = tls.NewCertificates()
+		reload = defaultReload
+	)
 	for c.Next() {
 		args := c.RemainingArgs()
 		if len(args) < 2 || len(args) > 3 {
-			return plugin.Error("tls", c.ArgErr())
+			return nil, nil, 0, c.ArgErr()
 		}
-		tls, err := tls.NewTLSConfigFromArgs(args...)
+		var err error
+		tc, err = tls.NewTLSConfigFromArgs(args...)
 		if err != nil {
-			return plugin.Error("tls", err)
+			return nil, nil, 0, err
+		}
+		if err := certs.Add(args[0], args[1]); err != nil {
+			return nil, nil, 0, err
+		}
+		// The CA, if given, is used to verify client certificates.
+		tc.ClientCAs = tc.RootCAs
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "cert":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, nil, 0, c.ArgErr()
+				}
+				if err := certs.Add(args[0], args[1]); err != nil {
+					return nil, nil, 0, err
+				}
+			case "client_auth":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, nil, 0, c.ArgErr()
+				}
+				auth, ok := clientAuth[args[0]]
+				if !ok {
+					return nil, nil, 0, c.Errf("unknown client_auth '%s'", args[0])
+				}
+				tc.ClientAuth = auth
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, nil, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, nil, 0, err
+				}
+				if d < 0 {
+					return nil, nil, 0, fmt.Errorf("reload can't be negative: %s", d)
+				}
+				reload = d
+			default:
+				return nil, nil, 0, c.Errf("unknown property '%s'", c.Val())
+			}
 		}
-		config.TLSConfig = tls
 	}
-	return nil
+	if tc == nil {
+		return nil, nil, 0, c.ArgErr()
+	}
+
+	// Certificates are picked by SNI from certs, which also swaps them in when the files change.
+	tc.Certificates = nil
+	tc.GetCertificate = certs.GetCertificate
+	return tc, certs, reload, nil
+}
+
+// periodicReload reloads the certificates every interval until the returned channel is closed. An
+// interval of 0 disables reloading.
+func periodicReload(certs *tls.Certificates, interval time.Duration) chan bool {
+	stop := make(chan bool)
+
+	if interval == 0 {
+		return stop
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				changed, err := certs.Reload()
+				if err != nil {
+					log.Warningf("Failed to reload certificates: %s", err)
+				}
+				if changed {
+					log.Infof("Reloaded certificates")
+				}
+			}
+		}
+	}()
+	return stop
 }
+
+// clientAuth maps the values of the client_auth property to the TLS client authentication policies.
+var clientAuth = map[string]ctls.ClientAuthType{
+	"nocert":             ctls.NoClientCert,
+	"request":            ctls.RequestClientCert,
+	"require":            ctls.RequireAnyClientCert,
+	"verify_if_given":    ctls.VerifyClientCertIfGiven,
+	"require_and_verify": ctls.RequireAndVerifyClientCert,
+}
+
+const defaultReload = time.Minute
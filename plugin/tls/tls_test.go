@@ -1,9 +1,14 @@
 package tls
 
 import (
+	ctls "crypto/tls"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/test"
+
 	"github.com/mholt/caddy"
 )
 
@@ -38,3 +43,56 @@ func TestTLS(t *testing.T) {
 		}
 	}
 }
+
+func TestTLSOptions(t *testing.T) {
+	dir, rmFunc, err := test.WritePEMFiles("")
+	if err != nil {
+		t.Fatalf("Could not write PEM files: %s", err)
+	}
+	defer rmFunc()
+	cert, key, ca := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		clientAuth ctls.ClientAuthType
+		clientCAs  bool
+	}{
+		{fmt.Sprintf("tls %s %s", cert, key), false, ctls.NoClientCert, false},
+		{fmt.Sprintf("tls %s %s %s {\nclient_auth require_and_verify\n}", cert, key, ca), false, ctls.RequireAndVerifyClientCert, true},
+		{fmt.Sprintf("tls %s %s {\ncert %s %s\nreload 10s\n}", cert, key, cert, key), false, ctls.NoClientCert, false},
+		{fmt.Sprintf("tls %s %s {\nreload 0s\n}", cert, key), false, ctls.NoClientCert, false},
+		// negative
+		{fmt.Sprintf("tls %s", cert), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\nclient_auth sometimes\n}", cert, key), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\nclient_auth\n}", cert, key), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\ncert %s\n}", cert, key, cert), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\ncert %s %s\n}", cert, key, ca, ca), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\nreload -1s\n}", cert, key), true, 0, false},
+		{fmt.Sprintf("tls %s %s {\nblah\n}", cert, key), true, 0, false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		cfg, _, _, err := parseTLS(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, tc.input, err)
+			continue
+		}
+		if cfg.ClientAuth != tc.clientAuth {
+			t.Errorf("Test %d: expected client auth %v, got %v", i, tc.clientAuth, cfg.ClientAuth)
+		}
+		if (cfg.ClientCAs != nil) != tc.clientCAs {
+			t.Errorf("Test %d: expected client CAs to be set: %t", i, tc.clientCAs)
+		}
+		if cfg.GetCertificate == nil {
+			t.Errorf("Test %d: expected GetCertificate to be set", i)
+		}
+	}
+}
@@ -1,22 +1,67 @@
 package request
 
-import "github.com/miekg/dns"
+import (
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
 
 // ScrubWriter will, when writing the message, call scrub to make it fit the client's buffer.
 type ScrubWriter struct {
 	dns.ResponseWriter
 	req *dns.Msg // original request
+	pad bool     // pad replies to clients that sent a padding option
 }
 
 // NewScrubWriter returns a new and initialized ScrubWriter.
-func NewScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter { return &ScrubWriter{w, req} }
+func NewScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter {
+	return &ScrubWriter{ResponseWriter: w, req: req}
+}
+
+// NewPaddingScrubWriter returns a new and initialized ScrubWriter that also pads the replies (RFC 7830)
+// for clients that sent a padding option. This is only useful for encrypted transports.
+func NewPaddingScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter {
+	return &ScrubWriter{ResponseWriter: w, req: req, pad: true}
+}
 
 // WriteMsg overrides the default implementation of the underlying dns.ResponseWriter and calls
-// scrub on the message m and will then write it to the client.
+// scrub on the message m and will then write it to the client. Extended DNS Errors in m are kept, even
+// when scrubbing removes the OPT record. A reply to a request without an OPT record can't have one, so
+// it is removed from m in that case. Likewise a client subnet option is only returned to clients that sent
+// one. The padding of m is removed, and if enabled m is padded following RFC 8467 without exceeding
+// the client's buffer size.
 func (s *ScrubWriter) WriteMsg(m *dns.Msg) error {
 	state := Request{Req: s.req, W: s.ResponseWriter}
 
+	ede := edns.ExtendedErrors(m)
 	n := state.Scrub(m)
-	state.SizeAndDo(n)
+	if !state.SizeAndDo(n) {
+		removeOPT(n)
+		return s.ResponseWriter.WriteMsg(n)
+	}
+	if edns.ClientSubnet(n) != nil && edns.ClientSubnet(s.req) == nil {
+		edns.SetClientSubnet(n, nil)
+	}
+	if len(edns.ExtendedErrors(n)) == 0 {
+		for _, e := range ede {
+			edns.AddExtendedError(n, e)
+		}
+	}
+	if s.pad && edns.Padding(s.req) != nil {
+		edns.Pad(n, edns.ResponseBlockSize, state.Size())
+	} else {
+		edns.RemovePadding(n)
+	}
 	return s.ResponseWriter.WriteMsg(n)
 }
+
+// removeOPT removes the OPT record from m.
+func removeOPT(m *dns.Msg) {
+	for i, rr := range m.Extra {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			extra := make([]dns.RR, 0, len(m.Extra)-1)
+			m.Extra = append(append(extra, m.Extra[:i]...), m.Extra[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package request
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestScrubWriterExtendedError(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(512, false)
+
+	// A reply that doesn't fit in 512 bytes; scrubbing drops its OPT record.
+	reply := new(dns.Msg)
+	reply.SetRcode(req, dns.RcodeServerFailure)
+	for i := 0; i < 40; i++ {
+		reply.Extra = append(reply.Extra, test.A("example.com. 300 IN A 127.0.0.1"))
+	}
+	edns.AddExtendedError(reply, edns.ExtendedError{InfoCode: edns.ExtendedErrorCodeNetworkError, ExtraText: "i/o timeout"})
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	NewScrubWriter(req, rec).WriteMsg(reply)
+	if ede := edns.ExtendedErrors(rec.Msg); len(ede) != 1 || ede[0].InfoCode != edns.ExtendedErrorCodeNetworkError {
+		t.Errorf("Expected extended error to be kept, got %v", ede)
+	}
+}
+
+func TestScrubWriterNoEdns(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+
+	reply := new(dns.Msg)
+	reply.SetRcode(req, dns.RcodeServerFailure)
+	edns.AddExtendedError(reply, edns.ExtendedError{InfoCode: edns.ExtendedErrorCodeNetworkError})
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	NewScrubWriter(req, rec).WriteMsg(reply)
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record in reply to request without one")
+	}
+}
+
+func TestScrubWriterClientSubnet(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(4096, false)
+
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	edns.SetClientSubnet(reply, edns.NewClientSubnet(net.ParseIP("192.0.2.1"), 24, 56))
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	NewScrubWriter(req, rec).WriteMsg(reply)
+	if e := edns.ClientSubnet(rec.Msg); e != nil {
+		t.Errorf("Expected no client subnet in reply to request without one, got %s", e)
+	}
+}
+
+func TestScrubWriterPadding(t *testing.T) {
+	tests := []struct {
+		pad        bool // use a padding ScrubWriter
+		reqPadding bool // the request has a padding option
+		padded     bool
+	}{
+		{true, true, true},
+		{true, false, false},
+		{false, true, false},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		if tc.reqPadding {
+			o := req.IsEdns0()
+			o.Option = append(o.Option, &dns.EDNS0_PADDING{Padding: make([]byte, 50)})
+		}
+
+		reply := new(dns.Msg)
+		reply.SetReply(req)
+		reply.Answer = append(reply.Answer, test.A("example.com. 300 IN A 127.0.0.1"))
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		w := NewScrubWriter(req, rec)
+		if tc.pad {
+			w = NewPaddingScrubWriter(req, rec)
+		}
+		w.WriteMsg(reply)
+
+		if padded := edns.Padding(rec.Msg) != nil; padded != tc.padded {
+			t.Errorf("Test %d: expected padded to be %t, got %t", i, tc.padded, padded)
+		}
+		if tc.padded && rec.Msg.Len()%edns.ResponseBlockSize != 0 {
+			t.Errorf("Test %d: expected length to be a multiple of %d, got %d", i, edns.ResponseBlockSize, rec.Msg.Len())
+		}
+	}
+}
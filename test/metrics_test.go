@@ -223,3 +223,53 @@ example.com:0 {
 		t.Errorf("Expected value %s for %s, but got %s", "", metricName, got)
 	}
 }
+
+// The queries in TestAutoPathMetrics are counted in the request metrics, so it comes after the tests
+// that check those.
+func TestAutoPathMetrics(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	addrMetrics := "localhost:9156"
+
+	corefile := `example.org:0 {
+		autopath example.org none {
+			client 127.0.0.0/8 a.example.org example.org
+			client ::1/128 a.example.org example.org
+		}
+		file ` + name + `
+		prometheus ` + addrMetrics + `
+}
+`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// Other tests use autopath as well, so look at how much the counters go up.
+	names := []string{"coredns_autopath_success_count_total", "coredns_autopath_round_trips_saved_total"}
+	before := make([]int, len(names))
+	for j, name := range names {
+		before[j] = test.ScrapeMetricAsInt(addrMetrics, name, "", 0)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("short.a.example.org.", dns.TypeA)
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) != 2 {
+		t.Fatalf("Expected CNAME and A record, got %v", resp.Answer)
+	}
+
+	for j, name := range names {
+		if got := test.ScrapeMetricAsInt(addrMetrics, name, "", -1); got != before[j]+1 {
+			t.Errorf("Expected value %d for %s, got %d", before[j]+1, name, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestProxyProtocol(t *testing.T) {
+	corefile := `.:0 {
+		proxyproto 127.0.0.1 ::1
+		whoami
+}
+`
+
+	i, udp, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	// TCP with a version 1 header.
+	conn, err := net.DialTimeout("tcp", tcp, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Could not connect: %s", err)
+	}
+	conn.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 53000 53\r\n"))
+	co := &dns.Conn{Conn: conn}
+	co.SetDeadline(time.Now().Add(2 * time.Second))
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	resp, err := co.ReadMsg()
+	co.Close()
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if x := whoamiIP(resp); x != "192.0.2.1" {
+		t.Errorf("Expected client address 192.0.2.1 over TCP, got %s", x)
+	}
+
+	// UDP with a version 2 header.
+	hdr := []byte("\r\n\r\n\x00\r\nQUIT\n\x21\x12\x00\x0c")
+	hdr = append(hdr, 192, 0, 2, 2, 127, 0, 0, 1, 0xcf, 0x08, 0, 53)
+	buf, _ := m.Pack()
+
+	pc, err := net.DialTimeout("udp", udp, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Could not connect: %s", err)
+	}
+	defer pc.Close()
+	pc.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := pc.Write(append(hdr, buf...)); err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	reply := make([]byte, dns.MaxMsgSize)
+	n, err := pc.Read(reply)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	resp = new(dns.Msg)
+	if err := resp.Unpack(reply[:n]); err != nil {
+		t.Fatalf("Could not unpack reply: %s", err)
+	}
+	if x := whoamiIP(resp); x != "192.0.2.2" {
+		t.Errorf("Expected client address 192.0.2.2 over UDP, got %s", x)
+	}
+}
+
+// whoamiIP returns the address the whoami plugin saw the query coming from.
+func whoamiIP(m *dns.Msg) string {
+	for _, rr := range m.Extra {
+		switch x := rr.(type) {
+		case *dns.A:
+			return x.A.String()
+		case *dns.AAAA:
+			return x.AAAA.String()
+		}
+	}
+	return ""
+}
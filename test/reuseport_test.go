@@ -0,0 +1,89 @@
+package test
+
+import (
+	"net"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestReusePort(t *testing.T) {
+	corefile := `.:0 {
+		reuseport 4
+		whoami
+}
+`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	// Each exchange uses a new source port, so the queries are spread over the sockets.
+	for j := 0; j < 20; j++ {
+		resp, err := dns.Exchange(m, udp)
+		if err != nil {
+			t.Fatalf("Expected to receive reply, but didn't: %s", err)
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[resp.Rcode])
+		}
+	}
+}
+
+// BenchmarkReusePort measures the queries per second over UDP from many clients, with a single socket
+// and with a socket per CPU. The difference only shows on hosts with multiple CPUs.
+func BenchmarkReusePort(b *testing.B) {
+	sockets := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		sockets = append(sockets, n)
+	}
+	for _, n := range sockets {
+		b.Run("sockets-"+strconv.Itoa(n), func(b *testing.B) {
+			corefile := `.:0 {
+		reuseport ` + strconv.Itoa(n) + `
+		whoami
+}
+`
+			i, udp, _, err := CoreDNSServerAndPorts(corefile)
+			if err != nil {
+				b.Fatalf("Could not get CoreDNS serving instance: %s", err)
+			}
+			defer i.Stop()
+
+			m := new(dns.Msg)
+			m.SetQuestion("example.org.", dns.TypeA)
+			buf, _ := m.Pack()
+
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				conn, err := net.Dial("udp", udp)
+				if err != nil {
+					b.Errorf("Could not connect: %s", err)
+					return
+				}
+				defer conn.Close()
+				reply := make([]byte, dns.MaxMsgSize)
+				for pb.Next() {
+					conn.SetDeadline(time.Now().Add(2 * time.Second))
+					if _, err := conn.Write(buf); err != nil {
+						b.Errorf("Could not send message: %s", err)
+						return
+					}
+					if _, err := conn.Read(reply); err != nil {
+						b.Errorf("Expected to receive reply, but didn't: %s", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
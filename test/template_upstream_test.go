@@ -69,3 +69,41 @@ func TestTemplateUpstream(t *testing.T) {
 		t.Fatalf("Failed to get address for CNAME, expected target.example.net. got %s", x)
 	}
 }
+
+func TestTemplateUpstreamConcurrency(t *testing.T) {
+	// The upstream lookup for the CNAME target must not count as a second query in flight.
+	corefile := `.:0 {
+		concurrency 1
+		template IN A cname.example.net. {
+			match ".*"
+			answer "cname.example.net. 60 IN CNAME target.example.net."
+			upstream
+		}
+		template IN A target.example.net. {
+			match ".*"
+			answer "target.example.net. 60 IN A 1.2.3.4"
+		}
+}
+`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("cname.example.net.", dns.TypeA)
+	r, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Could not send msg: %s", err)
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[r.Rcode])
+	}
+	if len(r.Answer) != 2 {
+		t.Fatalf("Expected 2 answers, got %d", len(r.Answer))
+	}
+	if x := r.Answer[1].(*dns.A).A.String(); x != "1.2.3.4" {
+		t.Errorf("Expected 1.2.3.4, got %s", x)
+	}
+}